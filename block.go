@@ -0,0 +1,201 @@
+// +build linux
+
+package udev
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"syscall"
+)
+
+// sectorSize is the unit of the size sys attribute of block devices, regardless of their logical block size
+const sectorSize = 512
+
+// BlockDevice is a view of a device of the block subsystem, a disk or a partition,
+// with its sizes and queue attributes from the kernel and its partition, filesystem and drive properties from udev.
+type BlockDevice struct {
+	*Device
+}
+
+// NewBlockDevice returns a pointer to a new block device view of the device, and an *Error if it is no block device
+func NewBlockDevice(d *Device) (*BlockDevice, error) {
+	if d.Subsystem() != "block" {
+		return nil, newError("NewBlockDevice", d.Syspath(), syscall.ENOTBLK)
+	}
+	return &BlockDevice{Device: d}, nil
+}
+
+// IsPartition reports whether the block device is a partition rather than a disk
+func (b *BlockDevice) IsPartition() bool {
+	return b.Devtype() == "partition"
+}
+
+// Disk returns the disk of a partition, or the block device itself if it is a disk
+func (b *BlockDevice) Disk() *BlockDevice {
+	if !b.IsPartition() {
+		return b
+	}
+	if d := b.ParentWithSubsystemDevtype("block", "disk"); d != nil {
+		return &BlockDevice{Device: d}
+	}
+	return nil
+}
+
+// Partitions returns the partitions of a disk, ordered by partition number, followed by
+// the partitions whose number can't be read
+func (b *BlockDevice) Partitions() ([]*BlockDevice, error) {
+	e := b.u.NewEnumerate()
+	if err := e.AddMatchParent(b.Device); err != nil {
+		return nil, err
+	}
+	if err := e.AddMatchSubsystem("block"); err != nil {
+		return nil, err
+	}
+	if err := e.AddMatchProperty("DEVTYPE", "partition"); err != nil {
+		return nil, err
+	}
+	devices, err := e.Devices()
+	if err != nil {
+		return nil, err
+	}
+	var r []*BlockDevice
+	for _, d := range devices {
+		if d.Syspath() != b.Syspath() {
+			r = append(r, &BlockDevice{Device: d})
+		}
+	}
+	// Partitions without a number are sorted last
+	numbers := make(map[*BlockDevice]int, len(r))
+	for _, p := range r {
+		n, err := p.PartitionNumber()
+		if err != nil {
+			n = math.MaxInt
+		}
+		numbers[p] = n
+	}
+	slices.SortStableFunc(r, func(x, y *BlockDevice) int {
+		return cmp.Compare(numbers[x], numbers[y])
+	})
+	return r, nil
+}
+
+// Size returns the size of the block device in bytes
+func (b *BlockDevice) Size() (uint64, error) {
+	n, err := sysattrUint(b.Device, "BlockDevice.Size", "size", 10, 64)
+	return n * sectorSize, err
+}
+
+// LogicalBlockSize returns the sector size of the block device in bytes, which is that of its disk for a partition
+func (b *BlockDevice) LogicalBlockSize() (int, error) {
+	return b.queueInt("BlockDevice.LogicalBlockSize", "logical_block_size")
+}
+
+// PhysicalBlockSize returns the physical sector size of the block device in bytes, which is that of its disk for a partition
+func (b *BlockDevice) PhysicalBlockSize() (int, error) {
+	return b.queueInt("BlockDevice.PhysicalBlockSize", "physical_block_size")
+}
+
+// Rotational reports whether the disk of the block device has rotating media
+func (b *BlockDevice) Rotational() (bool, error) {
+	n, err := b.queueInt("BlockDevice.Rotational", "rotational")
+	return n == 1, err
+}
+
+// queueInt parses a sys attribute of the request queue, which only disks have
+func (b *BlockDevice) queueInt(op, sysattr string) (int, error) {
+	d := b.Disk()
+	if d == nil {
+		return 0, newError(op, b.Syspath(), syscall.ENODEV)
+	}
+	return sysattrInt(d.Device, op, "queue/"+sysattr)
+}
+
+// Removable reports whether the disk of the block device has removable media
+func (b *BlockDevice) Removable() (bool, error) {
+	d := b.Disk()
+	if d == nil {
+		return false, newError("BlockDevice.Removable", b.Syspath(), syscall.ENODEV)
+	}
+	return sysattrBool(d.Device, "BlockDevice.Removable", "removable")
+}
+
+// ReadOnly reports whether the block device is read-only
+func (b *BlockDevice) ReadOnly() (bool, error) {
+	return sysattrBool(b.Device, "BlockDevice.ReadOnly", "ro")
+}
+
+// PartitionNumber returns the number of a partition, from the partition sys attribute or else the ID_PART_ENTRY_NUMBER property
+func (b *BlockDevice) PartitionNumber() (int, error) {
+	if _, ok := b.lookupSysattr("partition"); ok {
+		return sysattrInt(b.Device, "BlockDevice.PartitionNumber", "partition")
+	}
+	v := b.PropertyValue("ID_PART_ENTRY_NUMBER")
+	if v == "" {
+		return 0, newError("BlockDevice.PartitionNumber", b.Syspath(), syscall.ENOENT)
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, newError("BlockDevice.PartitionNumber", b.Syspath(), err)
+	}
+	return n, nil
+}
+
+// PartitionType returns the type of a partition, a GUID for GPT or a hexadecimal number like 0x83 for MBR
+func (b *BlockDevice) PartitionType() string {
+	return b.PropertyValue("ID_PART_ENTRY_TYPE")
+}
+
+// PartitionUUID returns the unique identifier of a partition, the PARTUUID
+func (b *BlockDevice) PartitionUUID() string {
+	return b.PropertyValue("ID_PART_ENTRY_UUID")
+}
+
+// PartitionName returns the name of a GPT partition, the PARTLABEL
+func (b *BlockDevice) PartitionName() string {
+	return b.PropertyValue("ID_PART_ENTRY_NAME")
+}
+
+// PartitionTableType returns the type of the partition table of a disk, or of the disk of a partition: gpt or dos
+func (b *BlockDevice) PartitionTableType() string {
+	if v := b.PropertyValue("ID_PART_TABLE_TYPE"); v != "" {
+		return v
+	}
+	return b.PropertyValue("ID_PART_ENTRY_SCHEME")
+}
+
+// FSType returns the type of the file system or other content of the block device, like ext4, vfat, swap or crypto_LUKS
+func (b *BlockDevice) FSType() string {
+	return b.PropertyValue("ID_FS_TYPE")
+}
+
+// FSUsage returns the usage of the content of the block device, like filesystem, other, raid or crypto
+func (b *BlockDevice) FSUsage() string {
+	return b.PropertyValue("ID_FS_USAGE")
+}
+
+// FSUUID returns the UUID of the file system of the block device
+func (b *BlockDevice) FSUUID() string {
+	return b.PropertyValue("ID_FS_UUID")
+}
+
+// FSLabel returns the label of the file system of the block device
+func (b *BlockDevice) FSLabel() string {
+	return b.PropertyValue("ID_FS_LABEL")
+}
+
+// FSVersion returns the version of the file system of the block device
+func (b *BlockDevice) FSVersion() string {
+	return b.PropertyValue("ID_FS_VERSION")
+}
+
+// Serial returns the serial of the disk, made of its model and serial number
+func (b *BlockDevice) Serial() string {
+	return b.PropertyValue("ID_SERIAL")
+}
+
+// Model returns the model of the disk
+func (b *BlockDevice) Model() string {
+	return b.PropertyValue("ID_MODEL")
+}
@@ -0,0 +1,153 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func ExampleBlockDevice() {
+
+	// Create Udev and Enumerate
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("block")
+	e.AddMatchProperty("DEVTYPE", "disk")
+
+	// List the disks with their size and partitions
+	devices, _ := e.Devices()
+	for _, d := range devices {
+		disk, _ := NewBlockDevice(d)
+		size, _ := disk.Size()
+		fmt.Println(disk.Devnode(), size, disk.Serial())
+		partitions, _ := disk.Partitions()
+		for _, p := range partitions {
+			fmt.Println(" ", p.Devnode(), p.FSType(), p.FSUUID())
+		}
+	}
+}
+
+func TestBlockDevice(t *testing.T) {
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("block")
+	e.AddMatchProperty("DEVTYPE", "disk")
+	devices, err := e.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range devices {
+		disk, err := NewBlockDevice(d)
+		if err != nil || disk.IsPartition() || disk.Disk() != disk {
+			t.Fatal("Wrong disk", d.Syspath(), err)
+		}
+		if _, err := disk.Size(); err != nil {
+			t.Error(err)
+		}
+		partitions, err := disk.Partitions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range partitions {
+			if !p.IsPartition() || p.Disk().Syspath() != disk.Syspath() {
+				t.Error("Wrong partition", p.Syspath())
+			}
+		}
+	}
+	null, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Skip(err)
+	}
+	if _, err := NewBlockDevice(null); !errors.Is(err, syscall.ENOTBLK) {
+		t.Error("No error for character device", err)
+	}
+}
+
+func TestFakeSysfsBlockDevice(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	d, _ := u.DeviceFromSubsystemSysname("block", "sda1")
+	sda1, err := NewBlockDevice(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sda1.IsPartition() || sda1.FSType() != "ext4" || sda1.FSUUID() != "1234" || sda1.PartitionType() != "0x83" {
+		t.Error("Wrong partition", sda1.Properties())
+	}
+	if n, err := sda1.PartitionNumber(); err != nil || n != 1 {
+		t.Error("Wrong partition number", n, err)
+	}
+	if n, err := sda1.Size(); err != nil || n != 1024*512 {
+		t.Error("Wrong size", n, err)
+	}
+	// The request queue is that of the disk
+	if n, err := sda1.LogicalBlockSize(); err != nil || n != 512 {
+		t.Error("Wrong sector size", n, err)
+	}
+	if r, err := sda1.Rotational(); err != nil || !r {
+		t.Error("Wrong rotational", r, err)
+	}
+	if r, err := sda1.Removable(); err != nil || r {
+		t.Error("Wrong removable", r, err)
+	}
+	// Missing sys attributes are errors
+	if _, err := sda1.ReadOnly(); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("No error for missing sys attribute", err)
+	}
+	if _, err := sda1.PhysicalBlockSize(); err == nil {
+		t.Error("No error for missing sys attribute")
+	}
+	sda := sda1.Disk()
+	if sda == nil || sda.Sysname() != "sda" || sda.IsPartition() || sda.Disk() != sda {
+		t.Fatal("Wrong disk")
+	}
+	if ro, err := sda.ReadOnly(); err != nil || ro {
+		t.Error("Wrong read-only", ro, err)
+	}
+	partitions, err := sda.Partitions()
+	if err != nil || len(partitions) != 1 || partitions[0].Sysname() != "sda1" {
+		t.Error("Wrong partitions", partitions, err)
+	}
+	null, _ := u.DeviceFromSubsystemSysname("mem", "null")
+	if _, err := NewBlockDevice(null); err == nil {
+		t.Error("No error for character device")
+	}
+}
+
+func TestFakeSysfsPartitions(t *testing.T) {
+	f := newTestSysfs(t)
+	sda := "/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda"
+	for _, p := range []fakeDevice{
+		{devpath: sda + "/sda10", sysattrs: map[string]string{"partition": "10"}},
+		{devpath: sda + "/sda2", sysattrs: map[string]string{"partition": "2"}},
+		// The number of a partition is read from udev without the partition sys attribute
+		{devpath: sda + "/sda3", initialized: true, properties: map[string]string{"ID_PART_ENTRY_NUMBER": "3"}},
+		{devpath: sda + "/sda4"},
+	} {
+		p.subsystem, p.devtype = "block", "partition"
+		f.add(p)
+	}
+	u := f.udev()
+	d, _ := u.DeviceFromSubsystemSysname("block", "sda")
+	disk, err := NewBlockDevice(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partitions, err := disk.Partitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sysnames []string
+	for _, p := range partitions {
+		sysnames = append(sysnames, p.Sysname())
+	}
+	// The partition without a number is sorted last
+	if strings.Join(sysnames, " ") != "sda1 sda2 sda3 sda10 sda4" {
+		t.Error("Wrong partitions", sysnames)
+	}
+}
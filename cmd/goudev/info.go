@@ -0,0 +1,146 @@
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	udev "github.com/jochenvg/go-udev"
+	"golang.org/x/sys/unix"
+)
+
+// deviceIDPattern matches the device IDs of the udev database: b8:0, c1:3, n2 or +pci:0000:00:1f.2
+var deviceIDPattern = regexp.MustCompile(`^([bc][0-9]+:[0-9]+|n[0-9]+|\+[^:]+:.+)$`)
+
+// info prints the device named by the argument and the attribute walk up its parents
+func info(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the device and its parents as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("info needs exactly one device")
+	}
+	u := udev.Udev{}
+	d, err := findDevice(&u, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshot(d))
+	}
+	s := d.Snapshot()
+	if err := udev.NewExportEncoder(w).Encode(&s); err != nil {
+		return err
+	}
+	return attributeWalk(d, w)
+}
+
+// findDevice returns the device named by a syspath, a devpath, a device node, a device ID or subsystem:sysname
+func findDevice(u *udev.Udev, name string) (*udev.Device, error) {
+	switch {
+	case strings.HasPrefix(name, "/sys/"):
+		return u.DeviceFromSyspath(name)
+	case strings.HasPrefix(name, "/devices/"):
+		return u.DeviceFromSyspath("/sys" + name)
+	case strings.HasPrefix(name, "/dev/"):
+		return deviceFromNode(u, name)
+	case deviceIDPattern.MatchString(name):
+		return u.DeviceFromDeviceID(name)
+	case strings.Contains(name, ":"):
+		i := strings.IndexByte(name, ':')
+		return u.DeviceFromSubsystemSysname(name[:i], name[i+1:])
+	}
+	// Like udevadm, a plain name is a device node in /dev
+	return deviceFromNode(u, "/dev/"+name)
+}
+
+// deviceFromNode returns the device of a device node, following symlinks like those in /dev/disk
+func deviceFromNode(u *udev.Udev, node string) (*udev.Device, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(node, &st); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: node, Err: err}
+	}
+	var t uint8
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFBLK:
+		t = 'b'
+	case unix.S_IFCHR:
+		t = 'c'
+	default:
+		return nil, fmt.Errorf("%s is no device node", node)
+	}
+	return u.DeviceFromDevnum(t, udev.MkDev(int(unix.Major(st.Rdev)), int(unix.Minor(st.Rdev))))
+}
+
+// snapshot captures the device and its parents, each with all of its sys attributes.
+// The chain is captured at once with the sys attributes of all devices, which each device only has its own of.
+func snapshot(d *udev.Device) udev.Snapshot {
+	var names []string
+	seen := make(map[string]bool)
+	for p := d; p != nil; p = p.Parent() {
+		for _, name := range sysattrs(p) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return d.Snapshot(names...)
+}
+
+// sysattrs returns the sys attributes of a device, without those udevadm leaves out of the attribute walk
+func sysattrs(d *udev.Device) (r []string) {
+	d.SysattrsSeq()(func(name string) bool {
+		if name != "uevent" && name != "dev" {
+			r = append(r, name)
+		}
+		return true
+	})
+	return
+}
+
+// attributeWalk prints the device and its parents in the udev rules key format, like udevadm info --attribute-walk
+func attributeWalk(d *udev.Device, w io.Writer) error {
+	fmt.Fprint(w, `
+Udevadm info starts with the device specified by the devpath and then
+walks up the chain of parent devices. It prints for every device
+found, all possible attributes in the udev rules key format.
+A rule to match, can be composed by the attributes of the device
+and the attributes from one single parent device.
+
+`)
+	for p, parent := d, false; p != nil; p, parent = p.Parent(), true {
+		suffix, kind := "", "device"
+		if parent {
+			suffix, kind = "S", "parent device"
+		}
+		fmt.Fprintf(w, "  looking at %s '%s':\n", kind, p.Devpath())
+		fmt.Fprintf(w, "    KERNEL%s==\"%s\"\n", suffix, p.Sysname())
+		fmt.Fprintf(w, "    SUBSYSTEM%s==\"%s\"\n", suffix, p.Subsystem())
+		fmt.Fprintf(w, "    DRIVER%s==\"%s\"\n", suffix, p.Driver())
+		for _, name := range sysattrs(p) {
+			v := strings.TrimRight(p.SysattrValue(name), " \t\n")
+			// Values which can't be matched by a rule are left out
+			if strings.IndexFunc(v, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+				continue
+			}
+			fmt.Fprintf(w, "    ATTR%s{%s}==\"%s\"\n", suffix, name, v)
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
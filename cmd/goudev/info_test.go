@@ -0,0 +1,57 @@
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	udev "github.com/jochenvg/go-udev"
+)
+
+func TestFindDevice(t *testing.T) {
+	u := udev.Udev{}
+	for _, name := range []string{"/sys/devices/virtual/mem/null", "/devices/virtual/mem/null", "/dev/null", "null", "c1:3", "+mem:null", "mem:null"} {
+		d, err := findDevice(&u, name)
+		if err != nil {
+			t.Errorf("%s not found: %v", name, err)
+			continue
+		}
+		if d.Syspath() != "/sys/devices/virtual/mem/null" {
+			t.Errorf("%s found as %s", name, d.Syspath())
+		}
+	}
+	for _, name := range []string{"/dev", "mem:missing", "b1:3"} {
+		if _, err := findDevice(&u, name); err == nil {
+			t.Errorf("%s found", name)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	var b bytes.Buffer
+	if err := info([]string{"/dev/null"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"P: /devices/virtual/mem/null\n", "E: SUBSYSTEM=mem\n", "looking at device '/devices/virtual/mem/null':\n", "    KERNEL==\"null\"\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("%q missing in %s", want, b.String())
+		}
+	}
+	b.Reset()
+	if err := info([]string{"--json", "mem:null"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	var s udev.Snapshot
+	if err := json.Unmarshal(b.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Sysname != "null" || s.Devnum != udev.MkDev(1, 3) {
+		t.Error("Wrong snapshot", s)
+	}
+	if err := info(nil, &b); err == nil {
+		t.Error("No error without a device")
+	}
+}
@@ -0,0 +1,55 @@
+// +build linux
+
+// Command goudev inspects udev devices like udevadm, using the udev package.
+//
+// Usage:
+//
+//	goudev info [--json] DEVICE
+//	goudev monitor [--kernel] [--udev] [--subsystem-match SUBSYSTEM[/DEVTYPE]] [--tag-match TAG] [--property KEY=VALUE] [--output human|json|udevadm]
+//
+// DEVICE is a syspath, a devpath, a device node, a device ID like b8:0 or n2, or subsystem:sysname.
+//
+// The monitor prints the events of both the kernel and udev unless one of them is selected, until interrupted.
+// The matches may be repeated, and patterns may be used as in udev rules. Devices match any of the
+// subsystems and any of the properties given, and all of the tags. Each property key may only be given once.
+// The JSON lines can be read back by a udev.Replay, and the udevadm output is that of udevadm monitor --property.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// commands maps the name of a subcommand to the function running it with its arguments
+var commands = map[string]func(args []string, w io.Writer) error{
+	"info":    info,
+	"monitor": monitor,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: goudev info [--json] DEVICE")
+	fmt.Fprintln(os.Stderr, "       goudev monitor [--kernel] [--udev] [--subsystem-match SUBSYSTEM[/DEVTYPE]] [--tag-match TAG] [--property KEY=VALUE] [--output human|json|udevadm]")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "-h", "--help", "help":
+		usage()
+		return
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "goudev: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd(os.Args[2:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "goudev:", err)
+		os.Exit(1)
+	}
+}
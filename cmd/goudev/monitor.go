@@ -0,0 +1,247 @@
+// +build linux
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	udev "github.com/jochenvg/go-udev"
+	"golang.org/x/sys/unix"
+)
+
+// listFlag is a flag which may be given more than once, collecting all of its values
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// event is a device received by one of the monitors, or an error of that monitor
+type event struct {
+	source string
+	d      udev.DeviceInfo
+	err    error
+}
+
+// jsonEvent is a line of the JSON output, which a udev.Replay can read back
+type jsonEvent struct {
+	Source string `json:"source"`
+	udev.Record
+}
+
+// printers maps the name of an output format to the function printing a device received in that format
+var printers = map[string]func(w io.Writer, source string, d udev.DeviceInfo) error{
+	"human":   printHuman,
+	"json":    printJSON,
+	"udevadm": printUdevadm,
+}
+
+// monitor prints the devices received from the kernel and udev until interrupted
+func monitor(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("monitor", flag.ContinueOnError)
+	kernel := fs.Bool("kernel", false, "print the kernel uevents")
+	udevEvents := fs.Bool("udev", false, "print the events udev sends out after rule processing")
+	output := fs.String("output", "human", "output `format`: human, json or udevadm")
+	var subsystems, tags, properties listFlag
+	fs.Var(&subsystems, "subsystem-match", "print only events of the `subsystem[/devtype]`, may be repeated")
+	fs.Var(&tags, "tag-match", "print only events of devices with the `tag`, may be repeated")
+	fs.Var(&properties, "property", "print only events of devices with the property `key=value`, may be repeated with different keys to match any of the properties")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	printDevice, ok := printers[*output]
+	if !ok {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	f, err := monitorFilter(subsystems, tags, properties)
+	if err != nil {
+		return err
+	}
+	// Like udevadm, both sources are monitored unless one is selected
+	var sources []string
+	if *kernel || !*udevEvents {
+		sources = append(sources, "kernel")
+	}
+	if *udevEvents || !*kernel {
+		sources = append(sources, "udev")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	u := udev.Udev{}
+	events := make(chan event)
+	var wg sync.WaitGroup
+	// send passes an event on, unless the monitors are stopping and nobody receives the events anymore
+	send := func(ev event) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	}
+	for _, source := range sources {
+		devices, errs, err := monitorSource(ctx, &u, source, f)
+		if err != nil {
+			// Stop the sources already started, which no longer have a receiver
+			cancel()
+			return err
+		}
+		wg.Add(1)
+		go func(source string) {
+			defer wg.Done()
+			for devices != nil || errs != nil {
+				select {
+				case d, ok := <-devices:
+					if !ok {
+						devices = nil
+						continue
+					}
+					send(event{source: source, d: d})
+				case err, ok := <-errs:
+					if !ok {
+						errs = nil
+						continue
+					}
+					send(event{source: source, err: err})
+				}
+			}
+		}(source)
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	if *output == "udevadm" {
+		fmt.Fprintln(w, "monitor will print the received events for:")
+		if slices.Contains(sources, "udev") {
+			fmt.Fprintln(w, "UDEV - the event which udev sends out after rule processing")
+		}
+		if slices.Contains(sources, "kernel") {
+			fmt.Fprintln(w, "KERNEL - the kernel uevent")
+		}
+		fmt.Fprintln(w)
+	}
+	var failed error
+	for ev := range events {
+		switch {
+		case ev.err != nil && errors.Is(ev.err, udev.ErrOverrun):
+			// Lost events are reported, but don't stop the monitor
+			fmt.Fprintf(os.Stderr, "goudev: %s: %v\n", ev.source, ev.err)
+		case ev.err != nil:
+			if failed == nil {
+				failed = fmt.Errorf("%s: %w", ev.source, ev.err)
+			}
+			cancel()
+		case failed == nil:
+			if err := printDevice(w, ev.source, ev.d); err != nil {
+				failed = err
+				cancel()
+			}
+		}
+	}
+	return failed
+}
+
+// monitorSource starts a monitor of the source with the filter installed, returning its channels
+func monitorSource(ctx context.Context, u *udev.Udev, source string, f *udev.Filter) (<-chan udev.DeviceInfo, <-chan error, error) {
+	m, err := u.MonitorFromNetlink(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := f.Install(m); err != nil {
+		return nil, nil, err
+	}
+	return m.DeviceInfoChan(ctx, udev.ChanOptions{Errors: true})
+}
+
+// monitorFilter returns the filter selecting the devices matched by the command line
+func monitorFilter(subsystems, tags, properties []string) (*udev.Filter, error) {
+	f := &udev.Filter{Tags: tags}
+	for _, s := range subsystems {
+		subsystem, devtype, _ := strings.Cut(s, "/")
+		if devtype != "" {
+			// The filter has a single device type for all subsystems
+			if f.Devtype != "" && f.Devtype != devtype {
+				return nil, fmt.Errorf("--subsystem-match %s: only one device type can be matched", s)
+			}
+			f.Devtype = devtype
+		}
+		f.Subsystems = append(f.Subsystems, subsystem)
+	}
+	for _, p := range properties {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--property %s: not a key=value pair", p)
+		}
+		if f.Properties == nil {
+			f.Properties = make(map[string]string)
+		}
+		// The filter has a single value for each property
+		if _, dup := f.Properties[key]; dup {
+			return nil, fmt.Errorf("--property %s: only one value can be matched for %s", p, key)
+		}
+		f.Properties[key] = value
+	}
+	return f, nil
+}
+
+// printHuman prints a line with the local time, the source, the action, the devpath, the subsystem and the device node
+func printHuman(w io.Writer, source string, d udev.DeviceInfo) error {
+	line := fmt.Sprintf("%s %-6s %-7s %s (%s)", time.Now().Format("15:04:05.000000"), source, d.Action(), d.Devpath(), d.Subsystem())
+	if n := d.Devnode(); n != "" {
+		line += " " + n
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// printJSON prints a JSON line with the time, the source and the record of the device
+func printJSON(w io.Writer, source string, d udev.DeviceInfo) error {
+	return json.NewEncoder(w).Encode(jsonEvent{
+		Source: source,
+		Record: udev.Record{
+			Time:        time.Now(),
+			Action:      d.Action(),
+			Seqnum:      d.Seqnum(),
+			Syspath:     d.Syspath(),
+			Initialized: d.IsInitialized(),
+			Properties:  d.Properties(),
+		},
+	})
+}
+
+// printUdevadm prints the event like udevadm monitor --property, with the monotonic time
+func printUdevadm(w io.Writer, source string, d udev.DeviceInfo) error {
+	var ts unix.Timespec
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-6s[%d.%06d] %-8s %s (%s)\n", strings.ToUpper(source), ts.Sec, ts.Nsec/1000, d.Action(), d.Devpath(), d.Subsystem())
+	properties := d.Properties()
+	for _, key := range slices.Sorted(maps.Keys(properties)) {
+		fmt.Fprintf(&b, "%s=%s\n", key, properties[key])
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
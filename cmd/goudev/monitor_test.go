@@ -0,0 +1,75 @@
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	udev "github.com/jochenvg/go-udev"
+)
+
+func TestMonitorFilter(t *testing.T) {
+	f, err := monitorFilter([]string{"block/disk", "scsi", "usb*"}, []string{"systemd"}, []string{"ID_BUS=ata", "ID_FS_TYPE=ext*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(f.Subsystems, " ") != "block scsi usb*" || f.Devtype != "disk" || len(f.Tags) != 1 {
+		t.Error("Wrong filter", f)
+	}
+	if len(f.Properties) != 2 || f.Properties["ID_FS_TYPE"] != "ext*" {
+		t.Error("Wrong properties", f.Properties)
+	}
+	if _, err := monitorFilter([]string{"block/disk", "block/partition"}, nil, nil); err == nil {
+		t.Error("No error for two device types")
+	}
+	if _, err := monitorFilter(nil, nil, []string{"ID_BUS"}); err == nil {
+		t.Error("No error for a property without value")
+	}
+	if _, err := monitorFilter(nil, nil, []string{"ID_BUS=ata", "ID_BUS=usb"}); err == nil {
+		t.Error("No error for two values of a property")
+	}
+}
+
+func TestPrinters(t *testing.T) {
+	d, err := udev.NewReplay(strings.NewReader(`{"action":"add","seqnum":7,"syspath":"/sys/devices/virtual/block/loop0",` +
+		`"properties":{"ACTION":"add","DEVPATH":"/devices/virtual/block/loop0","SUBSYSTEM":"block","DEVNAME":"/dev/loop0","SEQNUM":"7"}}`)).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"human", `^\d\d:\d\d:\d\d\.\d{6} udev   add     /devices/virtual/block/loop0 \(block\) /dev/loop0\n$`},
+		{"json", `^\{"source":"udev","time":"[^"]+","action":"add","seqnum":7,"syspath":"/sys/devices/virtual/block/loop0","properties":\{.*"SUBSYSTEM":"block"\}\}\n$`},
+		{"udevadm", `^UDEV  \[\d+\.\d{6}\] add      /devices/virtual/block/loop0 \(block\)\nACTION=add\n(\w+=.*\n)+\n$`},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := printers[test.output](&b, "udev", d); err != nil {
+			t.Fatal(err)
+		}
+		if !regexp.MustCompile(test.want).Match(b.Bytes()) {
+			t.Errorf("Wrong %s output %q", test.output, b.String())
+		}
+	}
+	// The JSON output can be replayed
+	var b bytes.Buffer
+	printJSON(&b, "kernel", d)
+	r, err := udev.NewReplay(&b).Next()
+	if err != nil || r.Syspath() != d.Syspath() || r.Seqnum() != 7 {
+		t.Error("JSON output not replayed", err)
+	}
+}
+
+func TestMonitorArguments(t *testing.T) {
+	var b bytes.Buffer
+	for _, args := range [][]string{{"--output", "xml"}, {"--property", "X"}, {"extra"}} {
+		if err := monitor(args, &b); err == nil {
+			t.Errorf("No error for %v", args)
+		}
+	}
+}
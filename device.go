@@ -1,17 +1,40 @@
-// +build linux,cgo
+// +build linux,cgo,!purego
 
 package udev
 
 /*
-  #cgo LDFLAGS: -ludev
+  #cgo LDFLAGS: -ludev -ldl
   #include <libudev.h>
   #include <linux/types.h>
   #include <stdlib.h>
 	#include <linux/kdev_t.h>
+  #include <dlfcn.h>
+
+  // udev_device_get_current_tags_list_entry is looked up at run time, as libudev before 247 lacks it.
+  // resolve_current_tags is called once by haveCurrentTags, before the function is called.
+  static struct udev_list_entry *(*current_tags_list_entry)(struct udev_device *);
+
+  static int resolve_current_tags(void) {
+    current_tags_list_entry = (struct udev_list_entry *(*)(struct udev_device *))dlsym(RTLD_DEFAULT, "udev_device_get_current_tags_list_entry");
+    return current_tags_list_entry != NULL;
+  }
+
+  static struct udev_list_entry *device_get_current_tags_list_entry(struct udev_device *udev_device) {
+    return current_tags_list_entry ? current_tags_list_entry(udev_device) : NULL;
+  }
 */
 import "C"
-import "errors"
-import "github.com/jkeiser/iter"
+import (
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// haveCurrentTags reports whether libudev has udev_device_get_current_tags_list_entry, which is looked up once
+var haveCurrentTags = sync.OnceValue(func() bool {
+	return C.resolve_current_tags() != 0
+})
 
 // Device wraps a libudev device object
 type Device struct {
@@ -45,13 +68,18 @@ func (d *Device) Parent() *Device {
 }
 
 // ParentWithSubsystemDevtype returns the parent Device with the given subsystem and devtype,
-// or nil if the receiver has no such parent device
+// or nil if the receiver has no such parent device.
+// An empty devtype matches parents of any devtype.
 func (d *Device) ParentWithSubsystemDevtype(subsystem, devtype string) *Device {
 	d.lock()
 	defer d.unlock()
-	ss, dt := C.CString(subsystem), C.CString(devtype)
+	ss := C.CString(subsystem)
 	defer freeCharPtr(ss)
-	defer freeCharPtr(dt)
+	var dt *C.char
+	if devtype != "" {
+		dt = C.CString(devtype)
+		defer freeCharPtr(dt)
+	}
 	ptr := C.udev_device_get_parent_with_subsystem_devtype(d.ptr, ss, dt)
 	if ptr != nil {
 		C.udev_device_ref(ptr)
@@ -135,28 +163,11 @@ func (d *Device) Devlinks() (r map[string]struct{}) {
 	return
 }
 
-// DevlinkIterator returns an Iterator over the device links pointing to the device file of the udev device.
-// The Iterator is using the github.com/jkeiser/iter package.
-// Values are returned as an interface{} and should be cast to string.
-func (d *Device) DevlinkIterator() iter.Iterator {
-	d.lock()
-	defer d.unlock()
-	l := C.udev_device_get_devlinks_list_entry(d.ptr)
-	return iter.Iterator{
-		Next: func() (item interface{}, err error) {
-			d.lock()
-			defer d.unlock()
-			if l != nil {
-				item = C.GoString(C.udev_list_entry_get_name(l))
-				l = C.udev_list_entry_get_next(l)
-			} else {
-				err = iter.FINISHED
-			}
-			return
-		},
-		Close: func() {
-		},
-	}
+// DevlinksSeq returns an iter.Seq over the device links pointing to the device file of the udev device.
+func (d *Device) DevlinksSeq() iter.Seq[string] {
+	return d.u.listNames(func() *C.struct_udev_list_entry {
+		return C.udev_device_get_devlinks_list_entry(d.ptr)
+	})
 }
 
 // Properties retrieves a map[string]string of key/value device properties of the udev device.
@@ -170,32 +181,11 @@ func (d *Device) Properties() (r map[string]string) {
 	return
 }
 
-// PropertyIterator returns an Iterator over the key/value device properties of the udev device.
-// The Iterator is using the github.com/jkeiser/iter package.
-// Values are returned as an interface{} and should be cast to []string,
-// which will have length 2 and represent a Key/Value pair.
-func (d *Device) PropertyIterator() iter.Iterator {
-	d.lock()
-	defer d.unlock()
-	l := C.udev_device_get_properties_list_entry(d.ptr)
-	return iter.Iterator{
-		Next: func() (item interface{}, err error) {
-			d.lock()
-			defer d.unlock()
-			if l != nil {
-				item = []string{
-					C.GoString(C.udev_list_entry_get_name(l)),
-					C.GoString(C.udev_list_entry_get_value(l)),
-				}
-				l = C.udev_list_entry_get_next(l)
-			} else {
-				err = iter.FINISHED
-			}
-			return
-		},
-		Close: func() {
-		},
-	}
+// PropertiesSeq returns an iter.Seq2 over the key/value device properties of the udev device.
+func (d *Device) PropertiesSeq() iter.Seq2[string, string] {
+	return d.u.listEntries(func() *C.struct_udev_list_entry {
+		return C.udev_device_get_properties_list_entry(d.ptr)
+	})
 }
 
 // Tags retrieves the Set of tags attached to the udev device.
@@ -209,28 +199,50 @@ func (d *Device) Tags() (r map[string]struct{}) {
 	return
 }
 
-// TagIterator returns an Iterator over the tags attached to the udev device.
-// The Iterator is using the github.com/jkeiser/iter package.
-// Values are returned as an interface{} and should be cast to string.
-func (d *Device) TagIterator() iter.Iterator {
+// TagsSeq returns an iter.Seq over the tags attached to the udev device.
+func (d *Device) TagsSeq() iter.Seq[string] {
+	return d.u.listNames(func() *C.struct_udev_list_entry {
+		return C.udev_device_get_tags_list_entry(d.ptr)
+	})
+}
+
+// CurrentTags retrieves the Set of tags still applying to the udev device, without the sticky tags
+// which were attached by earlier events only. With libudev before 247, all tags are current tags.
+func (d *Device) CurrentTags() (r map[string]struct{}) {
 	d.lock()
 	defer d.unlock()
-	l := C.udev_device_get_tags_list_entry(d.ptr)
-	return iter.Iterator{
-		Next: func() (item interface{}, err error) {
-			d.lock()
-			defer d.unlock()
-			if l != nil {
-				item = C.GoString(C.udev_list_entry_get_name(l))
-				l = C.udev_list_entry_get_next(l)
-			} else {
-				err = iter.FINISHED
-			}
-			return
-		},
-		Close: func() {
-		},
+	return currentTagsOf(d.ptr)
+}
+
+// CurrentTagsSeq returns an iter.Seq over the tags still applying to the udev device, see CurrentTags.
+func (d *Device) CurrentTagsSeq() iter.Seq[string] {
+	if !haveCurrentTags() {
+		return slices.Values(slices.Sorted(maps.Keys(d.CurrentTags())))
+	}
+	return d.u.listNames(func() *C.struct_udev_list_entry {
+		return C.device_get_current_tags_list_entry(d.ptr)
+	})
+}
+
+// currentTagsOf returns the Set of current tags of a libudev device while the Mutex is locked.
+// Without udev_device_get_current_tags_list_entry, the CURRENT_TAGS property is read if udev sets it,
+// and all tags are current otherwise.
+func currentTagsOf(ptr *C.struct_udev_device) map[string]struct{} {
+	l := C.udev_device_get_tags_list_entry(ptr)
+	if haveCurrentTags() {
+		l = C.device_get_current_tags_list_entry(ptr)
+	} else {
+		k := C.CString("CURRENT_TAGS")
+		defer freeCharPtr(k)
+		if v := C.udev_device_get_property_value(ptr, k); v != nil {
+			return setOf(currentTags(map[string]string{"CURRENT_TAGS": C.GoString(v)}, nil))
+		}
+	}
+	r := make(map[string]struct{})
+	for ; l != nil; l = C.udev_list_entry_get_next(l) {
+		r[C.GoString(C.udev_list_entry_get_name(l))] = struct{}{}
 	}
+	return r
 }
 
 // Sysattrs returns a Set with the systems attributes of the udev device.
@@ -244,28 +256,11 @@ func (d *Device) Sysattrs() (r map[string]struct{}) {
 	return
 }
 
-// SysattrIterator returns an Iterator over the systems attributes of the udev device.
-// The Iterator is using the github.com/jkeiser/iter package.
-// Values are returned as an interface{} and should be cast to string.
-func (d *Device) SysattrIterator() iter.Iterator {
-	d.lock()
-	defer d.unlock()
-	l := C.udev_device_get_sysattr_list_entry(d.ptr)
-	return iter.Iterator{
-		Next: func() (item interface{}, err error) {
-			d.lock()
-			defer d.unlock()
-			if l != nil {
-				item = C.GoString(C.udev_list_entry_get_name(l))
-				l = C.udev_list_entry_get_next(l)
-			} else {
-				err = iter.FINISHED
-			}
-			return
-		},
-		Close: func() {
-		},
-	}
+// SysattrsSeq returns an iter.Seq over the systems attributes of the udev device.
+func (d *Device) SysattrsSeq() iter.Seq[string] {
+	return d.u.listNames(func() *C.struct_udev_list_entry {
+		return C.udev_device_get_sysattr_list_entry(d.ptr)
+	})
 }
 
 // PropertyValue retrieves the value of a device property
@@ -330,6 +325,16 @@ func (d *Device) SysattrValue(sysattr string) string {
 	return C.GoString(C.udev_device_get_sysattr_value(d.ptr, s))
 }
 
+// lookupSysattr retrieves the content of a sys attribute file, and whether it could be read
+func (d *Device) lookupSysattr(sysattr string) (string, bool) {
+	d.lock()
+	defer d.unlock()
+	s := C.CString(sysattr)
+	defer freeCharPtr(s)
+	v := C.udev_device_get_sysattr_value(d.ptr, s)
+	return C.GoString(v), v != nil
+}
+
 // SetSysattrValue sets the content of a sys attribute file, and returns an error if this fails.
 func (d *Device) SetSysattrValue(sysattr, value string) (err error) {
 	d.lock()
@@ -337,10 +342,7 @@ func (d *Device) SetSysattrValue(sysattr, value string) (err error) {
 	sa, val := C.CString(sysattr), C.CString(value)
 	defer freeCharPtr(sa)
 	defer freeCharPtr(val)
-	if C.udev_device_set_sysattr_value(d.ptr, sa, val) < 0 {
-		err = errors.New("udev: udev_device_set_sysattr_value failed")
-	}
-	return
+	return errorFromReturn(C.udev_device_set_sysattr_value(d.ptr, sa, val), "udev_device_set_sysattr_value", sysattr)
 }
 
 // HasTag checks if the udev device has the tag specified
@@ -351,3 +353,9 @@ func (d *Device) HasTag(tag string) bool {
 	defer freeCharPtr(t)
 	return C.udev_device_has_tag(d.ptr, t) != 0
 }
+
+// HasCurrentTag checks if the tag specified still applies to the udev device, see CurrentTags
+func (d *Device) HasCurrentTag(tag string) bool {
+	_, ok := d.CurrentTags()[tag]
+	return ok
+}
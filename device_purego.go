@@ -0,0 +1,318 @@
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Device holds a udev device, read from sysfs and the udev database or received from a monitor
+type Device struct {
+	u *Udev
+
+	syspath         string
+	devpath         string
+	sysname         string
+	sysnum          string
+	subsystem       string
+	devtype         string
+	driver          string
+	devnode         string
+	devnum          Devnum
+	ifindex         int
+	action          string
+	seqnum          uint64
+	initialized     bool
+	usecInitialized uint64
+
+	properties map[string]string
+	devlinks   map[string]struct{}
+	tags       map[string]struct{}
+	// Tags still applying to the device, nil if udev does not distinguish them from sticky tags
+	currentTags map[string]struct{}
+	// Cache of sys attribute values read
+	sysattrs map[string]string
+
+	parentDevice *Device
+	parentRead   bool
+}
+
+// Lock the udev context
+func (d *Device) lock() {
+	d.u.m.Lock()
+}
+
+// Unlock the udev context
+func (d *Device) unlock() {
+	d.u.m.Unlock()
+}
+
+// Parent returns the parent Device, or nil if the receiver has no parent Device
+func (d *Device) Parent() *Device {
+	d.lock()
+	defer d.unlock()
+	return d.parent()
+}
+
+// ParentWithSubsystemDevtype returns the parent Device with the given subsystem and devtype,
+// or nil if the receiver has no such parent device.
+// An empty devtype matches parents of any devtype.
+func (d *Device) ParentWithSubsystemDevtype(subsystem, devtype string) *Device {
+	d.lock()
+	defer d.unlock()
+	for p := d.parent(); p != nil; p = p.parent() {
+		if p.subsystem == subsystem && (devtype == "" || p.devtype == devtype) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Devpath returns the kernel devpath value of the udev device.
+// The path does not contain the sys mount point, and starts with a '/'.
+func (d *Device) Devpath() string {
+	return d.devpath
+}
+
+// Subsystem returns the subsystem string of the udev device.
+// The string does not contain any "/".
+func (d *Device) Subsystem() string {
+	return d.subsystem
+}
+
+// Devtype returns the devtype string of the udev device.
+func (d *Device) Devtype() string {
+	return d.devtype
+}
+
+// Sysname returns the sysname of the udev device (e.g. ttyS3, sda1...).
+func (d *Device) Sysname() string {
+	return d.sysname
+}
+
+// Syspath returns the sys path of the udev device.
+// The path is an absolute path and starts with the sys mount point.
+func (d *Device) Syspath() string {
+	return d.syspath
+}
+
+// Sysnum returns the trailing number of of the device name
+func (d *Device) Sysnum() string {
+	return d.sysnum
+}
+
+// Devnode returns the device node file name belonging to the udev device.
+// The path is an absolute path, and starts with the device directory.
+func (d *Device) Devnode() string {
+	return d.devnode
+}
+
+// IsInitialized checks if udev has already handled the device and has set up
+// device node permissions and context, or has renamed a network device.
+//
+// This is only implemented for devices with a device node or network interfaces.
+// All other devices return 1 here.
+func (d *Device) IsInitialized() bool {
+	return d.initialized
+}
+
+// Devlinks retrieves the map of device links pointing to the device file of the udev device.
+// The path is an absolute path, and starts with the device directory.
+func (d *Device) Devlinks() (r map[string]struct{}) {
+	r = make(map[string]struct{})
+	for l := range d.devlinks {
+		r[l] = struct{}{}
+	}
+	return
+}
+
+// DevlinksSeq returns an iter.Seq over the device links pointing to the device file of the udev device.
+func (d *Device) DevlinksSeq() iter.Seq[string] {
+	return slices.Values(sortedKeys(d.devlinks))
+}
+
+// Properties retrieves a map[string]string of key/value device properties of the udev device.
+func (d *Device) Properties() (r map[string]string) {
+	r = make(map[string]string)
+	for k, v := range d.properties {
+		r[k] = v
+	}
+	return
+}
+
+// PropertiesSeq returns an iter.Seq2 over the key/value device properties of the udev device.
+func (d *Device) PropertiesSeq() iter.Seq2[string, string] {
+	keys := make([]string, 0, len(d.properties))
+	for k := range d.properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return func(yield func(string, string) bool) {
+		for _, k := range keys {
+			if !yield(k, d.properties[k]) {
+				return
+			}
+		}
+	}
+}
+
+// Tags retrieves the Set of tags attached to the udev device.
+func (d *Device) Tags() (r map[string]struct{}) {
+	r = make(map[string]struct{})
+	for t := range d.tags {
+		r[t] = struct{}{}
+	}
+	return
+}
+
+// TagsSeq returns an iter.Seq over the tags attached to the udev device.
+func (d *Device) TagsSeq() iter.Seq[string] {
+	return slices.Values(sortedKeys(d.tags))
+}
+
+// CurrentTags retrieves the Set of tags still applying to the udev device, without the sticky tags
+// which were attached by earlier events only. If the udev database has no current tags, all tags are current tags.
+func (d *Device) CurrentTags() (r map[string]struct{}) {
+	return setOf(d.currentTagList())
+}
+
+// CurrentTagsSeq returns an iter.Seq over the tags still applying to the udev device, see CurrentTags.
+func (d *Device) CurrentTagsSeq() iter.Seq[string] {
+	return slices.Values(d.currentTagList())
+}
+
+// currentTagList returns the sorted current tags of the device
+func (d *Device) currentTagList() []string {
+	if d.currentTags == nil {
+		return sortedKeys(d.tags)
+	}
+	return sortedKeys(d.currentTags)
+}
+
+// Sysattrs returns a Set with the systems attributes of the udev device.
+func (d *Device) Sysattrs() (r map[string]struct{}) {
+	r = make(map[string]struct{})
+	for _, s := range d.sysattrNames() {
+		r[s] = struct{}{}
+	}
+	return
+}
+
+// SysattrsSeq returns an iter.Seq over the systems attributes of the udev device.
+func (d *Device) SysattrsSeq() iter.Seq[string] {
+	return slices.Values(d.sysattrNames())
+}
+
+// PropertyValue retrieves the value of a device property
+func (d *Device) PropertyValue(key string) string {
+	return d.properties[key]
+}
+
+// Driver returns the driver for the receiver
+func (d *Device) Driver() string {
+	return d.driver
+}
+
+// Devnum returns the device major/minor number.
+func (d *Device) Devnum() Devnum {
+	return d.devnum
+}
+
+// Action returns the action for the event.
+// This is only valid if the device was received through a monitor.
+// Devices read from sys do not have an action string.
+// Usual actions are: add, remove, change, online, offline.
+func (d *Device) Action() string {
+	return d.action
+}
+
+// Seqnum returns the sequence number of the event.
+// This is only valid if the device was received through a monitor.
+// Devices read from sys do not have a sequence number.
+func (d *Device) Seqnum() uint64 {
+	return d.seqnum
+}
+
+// UsecSinceInitialized returns the number of microseconds passed since udev set up the device for the first time.
+// This is only implemented for devices with need to store properties in the udev database.
+// All other devices return 0 here.
+func (d *Device) UsecSinceInitialized() uint64 {
+	if d.usecInitialized == 0 {
+		return 0
+	}
+	var ts unix.Timespec
+	if unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts) != nil {
+		return 0
+	}
+	now := uint64(ts.Nano() / 1000)
+	if now < d.usecInitialized {
+		return 0
+	}
+	return now - d.usecInitialized
+}
+
+// SysattrValue retrieves the content of a sys attribute file, and returns an empty string if there is no sys attribute value.
+// The retrieved value is cached in the device.
+// Repeated calls will return the same value and not open the attribute again.
+func (d *Device) SysattrValue(sysattr string) string {
+	d.lock()
+	defer d.unlock()
+	v, _ := d.sysattrValue(sysattr)
+	return v
+}
+
+// lookupSysattr retrieves the content of a sys attribute file, and whether it could be read
+func (d *Device) lookupSysattr(sysattr string) (string, bool) {
+	d.lock()
+	defer d.unlock()
+	return d.sysattrValue(sysattr)
+}
+
+// SetSysattrValue sets the content of a sys attribute file, and returns an error if this fails.
+func (d *Device) SetSysattrValue(sysattr, value string) (err error) {
+	d.lock()
+	defer d.unlock()
+	path := filepath.Join(d.syspath, sysattr)
+	// Only regular files can be written to
+	fi, err := os.Lstat(path)
+	switch {
+	case err != nil:
+		return newError("udev_device_set_sysattr_value", sysattr, err)
+	case fi.IsDir():
+		return newError("udev_device_set_sysattr_value", sysattr, syscall.EISDIR)
+	case !fi.Mode().IsRegular():
+		return newError("udev_device_set_sysattr_value", sysattr, syscall.EINVAL)
+	case fi.Mode().Perm()&0200 == 0:
+		return newError("udev_device_set_sysattr_value", sysattr, syscall.EACCES)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return newError("udev_device_set_sysattr_value", sysattr, err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString(value); err != nil {
+		return newError("udev_device_set_sysattr_value", sysattr, err)
+	}
+	d.sysattrs[sysattr] = value
+	return
+}
+
+// HasTag checks if the udev device has the tag specified
+func (d *Device) HasTag(tag string) bool {
+	_, ok := d.tags[tag]
+	return ok
+}
+
+// HasCurrentTag checks if the tag specified still applies to the udev device, see CurrentTags
+func (d *Device) HasCurrentTag(tag string) bool {
+	_, ok := d.CurrentTags()[tag]
+	return ok
+}
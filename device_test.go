@@ -4,7 +4,10 @@ package udev
 
 import (
 	"fmt"
+	"maps"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -28,38 +31,31 @@ func ExampleDevice() {
 	fmt.Printf("Driver:%v\n", d.Driver())
 
 	// Use one of the iterators
-	it := d.PropertyIterator()
-	it.Each(func(item interface{}) {
-		kv := item.([]string)
-		_ = fmt.Sprintf("Property:%v=%v\n", kv[0], kv[1])
-	})
-	// Output:
-	// Sysname:zero
-	// Syspath:/sys/devices/virtual/mem/zero
-	// Devpath:/devices/virtual/mem/zero
-	// Devnode:/dev/zero
-	// Subsystem:mem
-	// Devtype:
-	// Sysnum:
-	// IsInitialized:true
-	// Driver:
+	for k, v := range d.PropertiesSeq() {
+		_ = fmt.Sprintf("Property:%v=%v\n", k, v)
+	}
 }
 
 func TestDeviceZero(t *testing.T) {
-	u := Udev{}
+	f := newTestSysfs(t)
+	f.add(fakeDevice{devpath: "/devices/virtual/mem/zero", subsystem: "mem", devname: "zero", devnum: MkDev(1, 5), initialized: true})
+	u := f.udev()
 	d := u.NewDeviceFromDeviceID("c1:5")
 	if d.Subsystem() != "mem" {
 		t.Fail()
 	}
-	if d.Sysname() != "zero" {
+	if d.Sysname() != "zero" || d.Sysnum() != "" {
 		t.Fail()
 	}
-	if d.Syspath() != "/sys/devices/virtual/mem/zero" {
+	if d.Syspath() != f.path("sys/devices/virtual/mem/zero") || d.Devpath() != "/devices/virtual/mem/zero" {
 		t.Fail()
 	}
 	if d.Devnode() != "/dev/zero" {
 		t.Fail()
 	}
+	if d.Devtype() != "" || d.Driver() != "" {
+		t.Fail()
+	}
 	if d.PropertyValue("SUBSYSTEM") != "mem" {
 		t.Fail()
 	}
@@ -79,12 +75,100 @@ func TestDeviceZero(t *testing.T) {
 	if len(sysattrs) == 0 {
 		t.Fail()
 	}
-	it := d.PropertyIterator()
-	it.Each(func(item interface{}) {
-		_ = item.([]string)
-	})
+	// The iterators should yield the same properties and sysattrs
+	n := 0
+	for k, v := range d.PropertiesSeq() {
+		if properties[k] != v {
+			t.Fail()
+		}
+		n++
+	}
+	if n != len(properties) {
+		t.Fail()
+	}
+	n = 0
+	for s := range d.SysattrsSeq() {
+		if _, ok := sysattrs[s]; !ok {
+			t.Fail()
+		}
+		n++
+	}
+	if n != len(sysattrs) {
+		t.Fail()
+	}
+	// Stopping early should not yield any further items
+	n = 0
+	for range d.PropertiesSeq() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fail()
+	}
 }
 
 func TestDeviceGC(t *testing.T) {
 	runtime.GC()
 }
+
+func TestCurrentTags(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Skip(err)
+	}
+	tags := d.CurrentTags()
+	for tag := range tags {
+		if !d.HasTag(tag) || !d.HasCurrentTag(tag) {
+			t.Error("Wrong current tag", tag)
+		}
+	}
+	if fmt.Sprint(slices.Collect(d.CurrentTagsSeq())) != fmt.Sprint(slices.Sorted(maps.Keys(tags))) {
+		t.Error("Wrong current tags sequence", slices.Collect(d.CurrentTagsSeq()))
+	}
+	// Without libudev support, the current tags are read from the CURRENT_TAGS property set by udev 247 and later
+	withoutCurrentTags(t)
+	if !maps.Equal(d.CurrentTags(), tags) || fmt.Sprint(slices.Collect(d.CurrentTagsSeq())) != fmt.Sprint(slices.Sorted(maps.Keys(tags))) {
+		t.Error("Wrong current tags without libudev support", d.CurrentTags())
+	}
+}
+
+func TestFakeSysfsCurrentTags(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	sda1, _ := u.DeviceFromSubsystemSysname("block", "sda1")
+	if !sda1.HasTag("uaccess") || sda1.HasCurrentTag("uaccess") || !sda1.HasCurrentTag("systemd") || len(sda1.CurrentTags()) != 1 {
+		t.Error("Wrong current tags", sda1.CurrentTags())
+	}
+	// Without current tags in the database, all tags are current
+	if sda := sda1.Parent(); fmt.Sprint(slices.Collect(sda.CurrentTagsSeq())) != "[systemd]" {
+		t.Error("Wrong current tags of parent", sda.CurrentTags())
+	}
+	tests := []struct {
+		setup    func(e *Enumerate)
+		sysnames string
+	}{
+		{func(e *Enumerate) { e.AddMatchTag("uaccess") }, "sda1"},
+		{func(e *Enumerate) { e.AddMatchCurrentTag("uaccess") }, ""},
+		{func(e *Enumerate) { e.AddMatchCurrentTag("systemd") }, "sda sda1"},
+		{func(e *Enumerate) { (&Filter{CurrentTags: []string{"systemd"}, Devtype: "partition"}).Apply(e) }, "sda1"},
+	}
+	for i, test := range tests {
+		e := u.NewEnumerate()
+		test.setup(e)
+		devices, err := e.Devices()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sysnames []string
+		for _, d := range devices {
+			sysnames = append(sysnames, d.Sysname())
+		}
+		if strings.Join(sysnames, " ") != test.sysnames {
+			t.Errorf("Enumerate %d found %v", i, sysnames)
+		}
+	}
+	if (&Filter{CurrentTags: []string{"uaccess"}}).Match(sda1) {
+		t.Error("Sticky tag matched as current tag")
+	}
+}
@@ -0,0 +1,256 @@
+// +build linux
+
+package udev
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// DeviceInfo is the read API of a device, implemented by *Device and by the in-memory *MemDevice.
+// Code accepting a DeviceInfo instead of a *Device can be tested without libudev or sysfs.
+type DeviceInfo interface {
+	Syspath() string
+	Devpath() string
+	Subsystem() string
+	Devtype() string
+	Sysname() string
+	Sysnum() string
+	Devnode() string
+	Driver() string
+	Devnum() Devnum
+	Action() string
+	Seqnum() uint64
+	IsInitialized() bool
+	Properties() map[string]string
+	PropertyValue(key string) string
+	Devlinks() map[string]struct{}
+	Tags() map[string]struct{}
+	HasTag(tag string) bool
+	CurrentTags() map[string]struct{}
+	HasCurrentTag(tag string) bool
+	Sysattrs() map[string]struct{}
+	SysattrValue(sysattr string) string
+	// ParentInfo returns the parent device, or nil if the device has no parent
+	ParentInfo() DeviceInfo
+}
+
+var (
+	_ DeviceInfo = (*Device)(nil)
+	_ DeviceInfo = (*MemDevice)(nil)
+)
+
+// ParentInfo returns the parent Device as a DeviceInfo, or nil if the receiver has no parent Device
+func (d *Device) ParentInfo() DeviceInfo {
+	if p := d.Parent(); p != nil {
+		return p
+	}
+	return nil
+}
+
+// MemDevice is a device held in memory, built from its properties and sys attributes or from a Snapshot
+type MemDevice struct {
+	s      Snapshot
+	parent DeviceInfo
+}
+
+// NewMemDevice returns a pointer to a new in-memory device with the parent, properties and sys attributes given.
+// The identity of the device is derived from its properties like for a uevent: the syspath from DEVPATH,
+// and the subsystem, devtype, driver, device node and number, action, sequence number, tags and device links
+// from the properties of the same name. The device is initialized if it has a USEC_INITIALIZED property.
+// The parent may be nil, and the maps are copied.
+func NewMemDevice(parent DeviceInfo, properties, sysattrs map[string]string) *MemDevice {
+	r := Record{
+		Syspath:    "/sys" + properties["DEVPATH"],
+		Properties: maps.Clone(properties),
+	}
+	if r.Properties == nil {
+		r.Properties = make(map[string]string)
+	}
+	m := &MemDevice{
+		s: Snapshot{
+			Syspath:    r.Syspath,
+			Devpath:    r.Properties["DEVPATH"],
+			Subsystem:  r.Properties["SUBSYSTEM"],
+			Devtype:    r.Properties["DEVTYPE"],
+			Sysname:    r.sysname(),
+			Sysnum:     r.sysnum(),
+			Devnode:    r.devnode(),
+			Driver:     r.Properties["DRIVER"],
+			Devnum:     r.devnum(),
+			Action:     r.Properties["ACTION"],
+			Properties: r.Properties,
+			Tags:       r.list("TAGS"),
+			Devlinks:   r.list("DEVLINKS"),
+			Sysattrs:   maps.Clone(sysattrs),
+		},
+		parent: parent,
+	}
+	m.s.Seqnum, _ = strconv.ParseUint(r.Properties["SEQNUM"], 10, 64)
+	_, m.s.Initialized = r.Properties["USEC_INITIALIZED"]
+	m.s.CurrentTags = currentTags(r.Properties, m.s.Tags)
+	return m
+}
+
+// NewMemDeviceFromSnapshot returns a pointer to a new in-memory device holding the values of the snapshot,
+// with the parents of the snapshot as its parents. The snapshot is copied.
+func NewMemDeviceFromSnapshot(s *Snapshot) *MemDevice {
+	m := &MemDevice{s: s.clone()}
+	if s.Parent != nil {
+		m.parent = NewMemDeviceFromSnapshot(s.Parent)
+	}
+	return m
+}
+
+// Syspath returns the sys path of the device
+func (m *MemDevice) Syspath() string {
+	return m.s.Syspath
+}
+
+// Devpath returns the kernel devpath value of the device
+func (m *MemDevice) Devpath() string {
+	return m.s.Devpath
+}
+
+// Subsystem returns the subsystem string of the device
+func (m *MemDevice) Subsystem() string {
+	return m.s.Subsystem
+}
+
+// Devtype returns the devtype string of the device
+func (m *MemDevice) Devtype() string {
+	return m.s.Devtype
+}
+
+// Sysname returns the sysname of the device
+func (m *MemDevice) Sysname() string {
+	return m.s.Sysname
+}
+
+// Sysnum returns the trailing number of of the device name
+func (m *MemDevice) Sysnum() string {
+	return m.s.Sysnum
+}
+
+// Devnode returns the device node file name belonging to the device
+func (m *MemDevice) Devnode() string {
+	return m.s.Devnode
+}
+
+// Driver returns the driver of the device
+func (m *MemDevice) Driver() string {
+	return m.s.Driver
+}
+
+// Devnum returns the device major/minor number
+func (m *MemDevice) Devnum() Devnum {
+	return m.s.Devnum
+}
+
+// Action returns the action for the event
+func (m *MemDevice) Action() string {
+	return m.s.Action
+}
+
+// Seqnum returns the sequence number of the event
+func (m *MemDevice) Seqnum() uint64 {
+	return m.s.Seqnum
+}
+
+// IsInitialized checks if the device was initialized by udev
+func (m *MemDevice) IsInitialized() bool {
+	return m.s.Initialized
+}
+
+// Properties retrieves a map[string]string of key/value device properties of the device
+func (m *MemDevice) Properties() map[string]string {
+	r := maps.Clone(m.s.Properties)
+	if r == nil {
+		r = make(map[string]string)
+	}
+	return r
+}
+
+// PropertyValue retrieves the value of a device property
+func (m *MemDevice) PropertyValue(key string) string {
+	return m.s.Properties[key]
+}
+
+// Devlinks retrieves the map of device links of the device
+func (m *MemDevice) Devlinks() map[string]struct{} {
+	return setOf(m.s.Devlinks)
+}
+
+// Tags retrieves the Set of tags attached to the device
+func (m *MemDevice) Tags() map[string]struct{} {
+	return setOf(m.s.Tags)
+}
+
+// HasTag checks if the device has the tag specified
+func (m *MemDevice) HasTag(tag string) bool {
+	return slices.Contains(m.s.Tags, tag)
+}
+
+// CurrentTags retrieves the Set of tags still applying to the device
+func (m *MemDevice) CurrentTags() map[string]struct{} {
+	return setOf(m.s.CurrentTags)
+}
+
+// HasCurrentTag checks if the tag specified still applies to the device
+func (m *MemDevice) HasCurrentTag(tag string) bool {
+	return slices.Contains(m.s.CurrentTags, tag)
+}
+
+// Sysattrs returns a Set with the systems attributes of the device
+func (m *MemDevice) Sysattrs() map[string]struct{} {
+	return setOf(slices.Collect(maps.Keys(m.s.Sysattrs)))
+}
+
+// SysattrValue retrieves the value of a sys attribute, and returns an empty string if there is no such sys attribute
+func (m *MemDevice) SysattrValue(sysattr string) string {
+	return m.s.Sysattrs[sysattr]
+}
+
+// lookupSysattr retrieves the value of a sys attribute, and whether the device has it
+func (m *MemDevice) lookupSysattr(sysattr string) (string, bool) {
+	v, ok := m.s.Sysattrs[sysattr]
+	return v, ok
+}
+
+// ParentInfo returns the parent device, or nil if the device has no parent
+func (m *MemDevice) ParentInfo() DeviceInfo {
+	return m.parent
+}
+
+// Snapshot returns the values of the device and of its parents
+func (m *MemDevice) Snapshot() Snapshot {
+	s := m.s.clone()
+	var ps Snapshot
+	switch p := m.parent.(type) {
+	case *MemDevice:
+		if p == nil {
+			return s
+		}
+		ps = p.Snapshot()
+	case *Device:
+		if p == nil {
+			return s
+		}
+		ps = p.Snapshot()
+	default:
+		// Parents of other types can't be captured
+		return s
+	}
+	s.Parent = &ps
+	return s
+}
+
+// setOf returns a Set of the strings given
+func setOf(l []string) map[string]struct{} {
+	r := make(map[string]struct{}, len(l))
+	for _, s := range l {
+		r[s] = struct{}{}
+	}
+	return r
+}
@@ -0,0 +1,93 @@
+// +build linux
+
+package udev
+
+import (
+	"fmt"
+	"testing"
+)
+
+// isUSBDisk is code under test, accepting any DeviceInfo
+func isUSBDisk(d DeviceInfo) bool {
+	return d.Subsystem() == "block" && d.Devtype() == "disk" && d.PropertyValue("ID_BUS") == "usb"
+}
+
+func ExampleMemDevice() {
+
+	// Create an in-memory disk, with a parent
+	usb := NewMemDevice(nil, map[string]string{
+		"DEVPATH":   "/devices/pci0000:00/0000:00:14.0/usb1/1-1",
+		"SUBSYSTEM": "usb",
+		"DEVTYPE":   "usb_device",
+	}, map[string]string{"idVendor": "0781"})
+	disk := NewMemDevice(usb, map[string]string{
+		"DEVPATH":   "/devices/pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0/host0/target0:0:0/0:0:0:0/block/sdb",
+		"SUBSYSTEM": "block",
+		"DEVTYPE":   "disk",
+		"DEVNAME":   "sdb",
+		"ID_BUS":    "usb",
+	}, nil)
+
+	fmt.Println(disk.Devnode(), isUSBDisk(disk), disk.ParentInfo().SysattrValue("idVendor"))
+	// Output: /dev/sdb true 0781
+}
+
+func TestMemDevice(t *testing.T) {
+	properties := map[string]string{
+		"ACTION":           "add",
+		"SEQNUM":           "1234",
+		"DEVPATH":          "/devices/virtual/block/loop0",
+		"SUBSYSTEM":        "block",
+		"DEVTYPE":          "disk",
+		"DEVNAME":          "/dev/loop0",
+		"MAJOR":            "7",
+		"MINOR":            "0",
+		"TAGS":             ":systemd:",
+		"DEVLINKS":         "/dev/disk/by-id/a /dev/disk/by-id/b",
+		"USEC_INITIALIZED": "1000",
+	}
+	d := NewMemDevice(nil, properties, map[string]string{"size": "0"})
+	// The maps are copied
+	properties["SUBSYSTEM"] = "mem"
+	if d.Syspath() != "/sys/devices/virtual/block/loop0" || d.Sysname() != "loop0" || d.Sysnum() != "0" || d.Subsystem() != "block" {
+		t.Error("Wrong identity", d.Syspath(), d.Sysname(), d.Subsystem())
+	}
+	if d.Action() != "add" || d.Seqnum() != 1234 || !d.IsInitialized() || d.Devnode() != "/dev/loop0" || d.Devnum() != MkDev(7, 0) {
+		t.Error("Wrong event", d.Action(), d.Seqnum(), d.Devnode())
+	}
+	if !d.HasTag("systemd") || len(d.Tags()) != 1 || len(d.Devlinks()) != 2 || len(d.Properties()) != 11 {
+		t.Error("Wrong lists", d.Tags(), d.Devlinks())
+	}
+	// Without a CURRENT_TAGS property, all tags are current
+	if !d.HasCurrentTag("systemd") || len(d.CurrentTags()) != 1 {
+		t.Error("Wrong current tags", d.CurrentTags())
+	}
+	properties["TAGS"] = ":systemd:uaccess:"
+	properties["CURRENT_TAGS"] = ":uaccess:"
+	if d := NewMemDevice(nil, properties, nil); !d.HasTag("systemd") || d.HasCurrentTag("systemd") || !d.HasCurrentTag("uaccess") {
+		t.Error("Wrong current tags", d.CurrentTags())
+	}
+	if d.SysattrValue("size") != "0" || len(d.Sysattrs()) != 1 || d.ParentInfo() != nil {
+		t.Error("Wrong sys attributes or parent")
+	}
+	// A device without properties
+	if d := NewMemDevice(nil, nil, nil); d.Subsystem() != "" || d.IsInitialized() || len(d.Properties()) != 0 {
+		t.Error("Empty device not empty")
+	}
+}
+
+func TestDeviceParentInfo(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A missing parent is a nil interface
+	if d.ParentInfo() != nil {
+		t.Error("Parent found")
+	}
+	var info DeviceInfo = d
+	if isUSBDisk(info) || info.Sysname() != "null" {
+		t.Error("Device does not implement DeviceInfo")
+	}
+}
@@ -0,0 +1,15 @@
+// +build linux
+
+// Package udev provides a cgo wrapper around the libudev C library
+//
+// When cgo is disabled, or when building with the purego build tag, a pure Go
+// implementation is used instead. It reads sysfs, the udev database in
+// /run/udev/data and the netlink uevent socket directly, and exposes the same
+// API. Binaries built this way do not link against libudev and can be built
+// statically or cross-compiled.
+//
+// BlockDevice, NetDevice, USBDevice and InputDevice are views of a Device of
+// their subsystem with typed accessors. Accessors of sys attributes return an
+// *Error when the attribute is missing or can't be parsed, while accessors of
+// udev properties return the zero value when the property is not set.
+package udev
@@ -1,4 +1,4 @@
-// +build linux,cgo
+// +build linux,cgo,!purego
 
 package udev
 
@@ -12,15 +12,19 @@ package udev
 import "C"
 
 import (
-	"errors"
-
-	"github.com/jkeiser/iter"
+	"iter"
+	"slices"
 )
 
 // Enumerate is an opaque struct wrapping a udev enumerate object.
 type Enumerate struct {
 	ptr *C.struct_udev_enumerate
 	u   *Udev
+
+	// Current tags matched, as libudev only matches tags including sticky ones
+	matchCurrentTag []string
+	// Device type matched along with properties, as libudev matches any of the properties
+	matchDevtype string
 }
 
 // Lock the udev context
@@ -44,10 +48,7 @@ func (e *Enumerate) AddMatchSubsystem(subsystem string) (err error) {
 	defer e.unlock()
 	s := C.CString(subsystem)
 	defer freeCharPtr(s)
-	if C.udev_enumerate_add_match_subsystem(e.ptr, s) != 0 {
-		err = errors.New("udev: udev_enumerate_add_match_subsystem failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_match_subsystem(e.ptr, s), "udev_enumerate_add_match_subsystem", subsystem)
 }
 
 // AddNomatchSubsystem adds a filter for a subsystem of the device to exclude from the list.
@@ -56,10 +57,7 @@ func (e *Enumerate) AddNomatchSubsystem(subsystem string) (err error) {
 	defer e.unlock()
 	s := C.CString(subsystem)
 	defer freeCharPtr(s)
-	if C.udev_enumerate_add_nomatch_subsystem(e.ptr, s) != 0 {
-		err = errors.New("udev: udev_enumerate_add_nomatch_subsystem failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_nomatch_subsystem(e.ptr, s), "udev_enumerate_add_nomatch_subsystem", subsystem)
 }
 
 // AddMatchSysattr adds a filter for a sys attribute at the device to include in the list.
@@ -69,10 +67,7 @@ func (e *Enumerate) AddMatchSysattr(sysattr, value string) (err error) {
 	s, v := C.CString(sysattr), C.CString(value)
 	defer freeCharPtr(s)
 	defer freeCharPtr(v)
-	if C.udev_enumerate_add_match_sysattr(e.ptr, s, v) != 0 {
-		err = errors.New("udev: udev_enumerate_add_match_sysattr failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_match_sysattr(e.ptr, s, v), "udev_enumerate_add_match_sysattr", sysattr)
 }
 
 // AddNomatchSysattr adds a filter for a sys attribute at the device to exclude from the list.
@@ -82,10 +77,7 @@ func (e *Enumerate) AddNomatchSysattr(sysattr, value string) (err error) {
 	s, v := C.CString(sysattr), C.CString(value)
 	defer freeCharPtr(s)
 	defer freeCharPtr(v)
-	if C.udev_enumerate_add_nomatch_sysattr(e.ptr, s, v) != 0 {
-		err = errors.New("udev: udev_enumerate_add_nomatch_sysattr failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_nomatch_sysattr(e.ptr, s, v), "udev_enumerate_add_nomatch_sysattr", sysattr)
 }
 
 // AddMatchProperty adds a filter for a property of the device to include in the list.
@@ -95,10 +87,7 @@ func (e *Enumerate) AddMatchProperty(property, value string) (err error) {
 	p, v := C.CString(property), C.CString(value)
 	defer freeCharPtr(p)
 	defer freeCharPtr(v)
-	if C.udev_enumerate_add_match_property(e.ptr, p, v) != 0 {
-		err = errors.New("udev: udev_enumerate_add_match_property failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_match_property(e.ptr, p, v), "udev_enumerate_add_match_property", property)
 }
 
 // AddMatchSysname adds a filter for the name of the device to include in the list.
@@ -107,10 +96,7 @@ func (e *Enumerate) AddMatchSysname(sysname string) (err error) {
 	defer e.unlock()
 	s := C.CString(sysname)
 	defer freeCharPtr(s)
-	if C.udev_enumerate_add_match_sysname(e.ptr, s) != 0 {
-		err = errors.New("udev: udev_enumerate_add_match_sysname failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_match_sysname(e.ptr, s), "udev_enumerate_add_match_sysname", sysname)
 }
 
 // AddMatchTag adds a filter for a tag of the device to include in the list.
@@ -119,20 +105,74 @@ func (e *Enumerate) AddMatchTag(tag string) (err error) {
 	defer e.unlock()
 	t := C.CString(tag)
 	defer freeCharPtr(t)
-	if C.udev_enumerate_add_match_tag(e.ptr, t) != 0 {
-		err = errors.New("udev: udev_enumerate_add_match_tag failed")
+	return errorFromReturn(C.udev_enumerate_add_match_tag(e.ptr, t), "udev_enumerate_add_match_tag", tag)
+}
+
+// AddMatchCurrentTag adds a filter for a tag still applying to the device to include in the list, see Device.CurrentTags.
+func (e *Enumerate) AddMatchCurrentTag(tag string) (err error) {
+	e.lock()
+	defer e.unlock()
+	t := C.CString(tag)
+	defer freeCharPtr(t)
+	if err = errorFromReturn(C.udev_enumerate_add_match_tag(e.ptr, t), "udev_enumerate_add_match_tag", tag); err == nil {
+		e.matchCurrentTag = append(e.matchCurrentTag, tag)
 	}
 	return
 }
 
+// addMatchDevtype adds a filter for the device type of the device to include in the list, evaluated after scanning
+func (e *Enumerate) addMatchDevtype(devtype string) {
+	e.lock()
+	defer e.unlock()
+	e.matchDevtype = devtype
+}
+
+// postMatching reports whether devices are matched after scanning, while the Mutex is locked
+func (e *Enumerate) postMatching() bool {
+	return len(e.matchCurrentTag) > 0 || e.matchDevtype != ""
+}
+
+// deviceMatches reports whether a libudev device has the device type and all current tags matched, while the Mutex is locked
+func (e *Enumerate) deviceMatches(ptr *C.struct_udev_device) bool {
+	if !e.postMatching() {
+		return true
+	}
+	if ptr == nil {
+		return false
+	}
+	if e.matchDevtype != "" && C.GoString(C.udev_device_get_devtype(ptr)) != e.matchDevtype {
+		return false
+	}
+	if len(e.matchCurrentTag) == 0 {
+		return true
+	}
+	tags := currentTagsOf(ptr)
+	for _, t := range e.matchCurrentTag {
+		if _, ok := tags[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// syspathMatches reports whether the device at the syspath is matched after scanning, while the Mutex is locked
+func (e *Enumerate) syspathMatches(syspath *C.char) bool {
+	if !e.postMatching() {
+		return true
+	}
+	ptr := C.udev_device_new_from_syspath(e.u.ptr, syspath)
+	if ptr == nil {
+		return false
+	}
+	defer C.udev_device_unref(ptr)
+	return e.deviceMatches(ptr)
+}
+
 // AddMatchParent adds a filter for a parent Device to include in the list.
 func (e *Enumerate) AddMatchParent(parent *Device) (err error) {
 	e.lock()
 	defer e.unlock()
-	if C.udev_enumerate_add_match_parent(e.ptr, parent.ptr) != 0 {
-		err = errors.New("udev: udev_enumerate_add_match_parent failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_match_parent(e.ptr, parent.ptr), "udev_enumerate_add_match_parent", C.GoString(C.udev_device_get_syspath(parent.ptr)))
 }
 
 // AddMatchIsInitialized adds a filter matching only devices which udev has set up already.
@@ -143,10 +183,7 @@ func (e *Enumerate) AddMatchParent(parent *Device) (err error) {
 func (e *Enumerate) AddMatchIsInitialized() (err error) {
 	e.lock()
 	defer e.unlock()
-	if C.udev_enumerate_add_match_is_initialized(e.ptr) != 0 {
-		err = errors.New("udev: udev_enumerate_add_match_is_initialized failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_match_is_initialized(e.ptr), "udev_enumerate_add_match_is_initialized", "")
 }
 
 // AddSyspath adds a device to the list of enumerated devices, to retrieve it back sorted in dependency order.
@@ -155,62 +192,54 @@ func (e *Enumerate) AddSyspath(syspath string) (err error) {
 	defer e.unlock()
 	s := C.CString(syspath)
 	defer freeCharPtr(s)
-	if C.udev_enumerate_add_syspath(e.ptr, s) != 0 {
-		err = errors.New("udev: udev_enumerate_add_syspath failed")
-	}
-	return
+	return errorFromReturn(C.udev_enumerate_add_syspath(e.ptr, s), "udev_enumerate_add_syspath", syspath)
 }
 
 // DeviceSyspaths retrieves a list of device syspaths matching the filter, sorted in dependency order.
 func (e *Enumerate) DeviceSyspaths() (s []string, err error) {
 	e.lock()
 	defer e.unlock()
-	if C.udev_enumerate_scan_devices(e.ptr) < 0 {
-		err = errors.New("udev: udev_enumerate_scan_devices failed")
+	if r := C.udev_enumerate_scan_devices(e.ptr); r < 0 {
+		err = errorFromReturn(r, "udev_enumerate_scan_devices", "")
 	} else {
 		s = make([]string, 0)
 		for l := C.udev_enumerate_get_list_entry(e.ptr); l != nil; l = C.udev_list_entry_get_next(l) {
-			s = append(s, C.GoString(C.udev_list_entry_get_name(l)))
+			if name := C.udev_list_entry_get_name(l); e.syspathMatches(name) {
+				s = append(s, C.GoString(name))
+			}
 		}
 	}
 	return
 }
 
-// DeviceSyspathIterator returns an Iterator over the device syspaths matching the filter, sorted in dependency order.
-// The Iterator is using the github.com/jkeiser/iter package.
-// Values are returned as an interface{} and should be cast to string.
-func (e *Enumerate) DeviceSyspathIterator() (it iter.Iterator, err error) {
+// DeviceSyspathsSeq scans the devices matching the filter and returns an iter.Seq over their syspaths, sorted in dependency order.
+func (e *Enumerate) DeviceSyspathsSeq() (iter.Seq[string], error) {
 	e.lock()
 	defer e.unlock()
-	if C.udev_enumerate_scan_devices(e.ptr) < 0 {
-		err = errors.New("udev: udev_enumerate_scan_devices failed")
-	} else {
-		l := C.udev_enumerate_get_list_entry(e.ptr)
-		it = iter.Iterator{
-			Next: func() (item interface{}, err error) {
-				e.lock()
-				defer e.unlock()
-				if l != nil {
-					item = C.GoString(C.udev_list_entry_get_name(l))
-					l = C.udev_list_entry_get_next(l)
-				} else {
-					err = iter.FINISHED
-				}
-				return
-			},
-			Close: func() {
-			},
+	if r := C.udev_enumerate_scan_devices(e.ptr); r < 0 {
+		return nil, errorFromReturn(r, "udev_enumerate_scan_devices", "")
+	}
+	if e.postMatching() {
+		// The devices are read to evaluate the matches after scanning
+		var s []string
+		for l := C.udev_enumerate_get_list_entry(e.ptr); l != nil; l = C.udev_list_entry_get_next(l) {
+			if name := C.udev_list_entry_get_name(l); e.syspathMatches(name) {
+				s = append(s, C.GoString(name))
+			}
 		}
+		return slices.Values(s), nil
 	}
-	return
+	return e.u.listNames(func() *C.struct_udev_list_entry {
+		return C.udev_enumerate_get_list_entry(e.ptr)
+	}), nil
 }
 
 // SubsystemSyspaths retrieves a list of subsystem syspaths matching the filter, sorted in dependency order.
 func (e *Enumerate) SubsystemSyspaths() (s []string, err error) {
 	e.lock()
 	defer e.unlock()
-	if C.udev_enumerate_scan_subsystems(e.ptr) < 0 {
-		err = errors.New("udev: udev_enumerate_scan_subsystems failed")
+	if r := C.udev_enumerate_scan_subsystems(e.ptr); r < 0 {
+		err = errorFromReturn(r, "udev_enumerate_scan_subsystems", "")
 	} else {
 		s = make([]string, 0)
 		for l := C.udev_enumerate_get_list_entry(e.ptr); l != nil; l = C.udev_list_entry_get_next(l) {
@@ -220,77 +249,53 @@ func (e *Enumerate) SubsystemSyspaths() (s []string, err error) {
 	return
 }
 
-// DeviceSubsystemIterator returns an Iterator over the subsystem syspaths matching the filter, sorted in dependency order.
-// The Iterator is using the github.com/jkeiser/iter package.
-// Values are returned as an interface{} and should be cast to string.
-func (e *Enumerate) DeviceSubsystemIterator() (it iter.Iterator, err error) {
+// SubsystemSyspathsSeq scans the subsystems matching the filter and returns an iter.Seq over their syspaths, sorted in dependency order.
+func (e *Enumerate) SubsystemSyspathsSeq() (iter.Seq[string], error) {
 	e.lock()
 	defer e.unlock()
-	if C.udev_enumerate_scan_subsystems(e.ptr) < 0 {
-		err = errors.New("udev: udev_enumerate_scan_devices failed")
-	} else {
-		l := C.udev_enumerate_get_list_entry(e.ptr)
-		it = iter.Iterator{
-			Next: func() (item interface{}, err error) {
-				e.lock()
-				defer e.unlock()
-				if l != nil {
-					item = C.GoString(C.udev_list_entry_get_name(l))
-					l = C.udev_list_entry_get_next(l)
-				} else {
-					err = iter.FINISHED
-				}
-				return
-			},
-			Close: func() {
-			},
-		}
+	if r := C.udev_enumerate_scan_subsystems(e.ptr); r < 0 {
+		return nil, errorFromReturn(r, "udev_enumerate_scan_subsystems", "")
 	}
-	return
+	return e.u.listNames(func() *C.struct_udev_list_entry {
+		return C.udev_enumerate_get_list_entry(e.ptr)
+	}), nil
 }
 
 // Devices retrieves a list of Devices matching the filter, sorted in dependency order.
 func (e *Enumerate) Devices() (m []*Device, err error) {
 	e.lock()
 	defer e.unlock()
-	if C.udev_enumerate_scan_devices(e.ptr) < 0 {
-		err = errors.New("udev: udev_enumerate_scan_devices failed")
+	if r := C.udev_enumerate_scan_devices(e.ptr); r < 0 {
+		err = errorFromReturn(r, "udev_enumerate_scan_devices", "")
 	} else {
 		m = make([]*Device, 0)
 		for l := C.udev_enumerate_get_list_entry(e.ptr); l != nil; l = C.udev_list_entry_get_next(l) {
 			s := C.udev_list_entry_get_name(l)
-			m = append(m, e.u.newDevice(C.udev_device_new_from_syspath(e.u.ptr, s)))
+			ptr := C.udev_device_new_from_syspath(e.u.ptr, s)
+			if !e.deviceMatches(ptr) {
+				if ptr != nil {
+					C.udev_device_unref(ptr)
+				}
+				continue
+			}
+			m = append(m, e.u.newDevice(ptr))
 		}
 	}
 	return
 }
 
-// DeviceIterator returns an Iterator over the Devices matching the filter, sorted in dependency order.
-// The Iterator is using the github.com/jkeiser/iter package.
-// Values are returned as an interface{} and should be cast to *Device.
-func (e *Enumerate) DeviceIterator() (it iter.Iterator, err error) {
-	e.lock()
-	defer e.unlock()
-	if C.udev_enumerate_scan_devices(e.ptr) < 0 {
-		err = errors.New("udev: udev_enumerate_scan_devices failed")
-	} else {
-		l := C.udev_enumerate_get_list_entry(e.ptr)
-		it = iter.Iterator{
-			Next: func() (item interface{}, err error) {
-				e.lock()
-				defer e.unlock()
-				if l != nil {
-					s := C.udev_list_entry_get_name(l)
-					item = e.u.newDevice(C.udev_device_new_from_syspath(e.u.ptr, s))
-					l = C.udev_list_entry_get_next(l)
-				} else {
-					err = iter.FINISHED
-				}
-				return
-			},
-			Close: func() {
-			},
+// DevicesSeq returns an iter.Seq2 over the Devices matching the filter, sorted in dependency order.
+// The devices are scanned when the iteration starts. A failing scan yields a nil Device and the error once,
+// a device which cannot be created, e.g. because it was removed since scanning, yields a nil Device and its error.
+func (e *Enumerate) DevicesSeq() iter.Seq2[*Device, error] {
+	return func(yield func(*Device, error) bool) {
+		s, err := e.DeviceSyspathsSeq()
+		if err != nil {
+			yield(nil, err)
+			return
 		}
+		s(func(syspath string) bool {
+			return yield(e.u.DeviceFromSyspath(syspath))
+		})
 	}
-	return
 }
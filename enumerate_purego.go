@@ -0,0 +1,460 @@
+//go:build linux && (!cgo || purego)
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Enumerate is an opaque struct holding the filters and results of a device enumeration.
+type Enumerate struct {
+	u *Udev
+
+	matchSubsystem     []string
+	nomatchSubsystem   []string
+	matchSysattr       [][2]string
+	nomatchSysattr     [][2]string
+	matchProperty      [][2]string
+	matchSysname       []string
+	matchTag           []string
+	matchCurrentTag    []string
+	matchDevtype       string
+	matchParent        *Device
+	matchIsInitialized bool
+
+	// Set of syspaths added with AddSyspath
+	added map[string]struct{}
+	// Set of syspaths found by the last scan
+	scanned map[string]struct{}
+}
+
+// Lock the udev context
+func (e *Enumerate) lock() {
+	e.u.m.Lock()
+}
+
+// Unlock the udev context
+func (e *Enumerate) unlock() {
+	e.u.m.Unlock()
+}
+
+// AddMatchSubsystem adds a filter for a subsystem of the device to include in the list.
+func (e *Enumerate) AddMatchSubsystem(subsystem string) (err error) {
+	e.lock()
+	defer e.unlock()
+	e.matchSubsystem = append(e.matchSubsystem, subsystem)
+	return
+}
+
+// AddNomatchSubsystem adds a filter for a subsystem of the device to exclude from the list.
+func (e *Enumerate) AddNomatchSubsystem(subsystem string) (err error) {
+	e.lock()
+	defer e.unlock()
+	e.nomatchSubsystem = append(e.nomatchSubsystem, subsystem)
+	return
+}
+
+// AddMatchSysattr adds a filter for a sys attribute at the device to include in the list.
+func (e *Enumerate) AddMatchSysattr(sysattr, value string) (err error) {
+	e.lock()
+	defer e.unlock()
+	e.matchSysattr = append(e.matchSysattr, [2]string{sysattr, value})
+	return
+}
+
+// AddNomatchSysattr adds a filter for a sys attribute at the device to exclude from the list.
+func (e *Enumerate) AddNomatchSysattr(sysattr, value string) (err error) {
+	e.lock()
+	defer e.unlock()
+	e.nomatchSysattr = append(e.nomatchSysattr, [2]string{sysattr, value})
+	return
+}
+
+// AddMatchProperty adds a filter for a property of the device to include in the list.
+func (e *Enumerate) AddMatchProperty(property, value string) (err error) {
+	e.lock()
+	defer e.unlock()
+	e.matchProperty = append(e.matchProperty, [2]string{property, value})
+	return
+}
+
+// AddMatchSysname adds a filter for the name of the device to include in the list.
+func (e *Enumerate) AddMatchSysname(sysname string) (err error) {
+	e.lock()
+	defer e.unlock()
+	e.matchSysname = append(e.matchSysname, sysname)
+	return
+}
+
+// AddMatchTag adds a filter for a tag of the device to include in the list.
+func (e *Enumerate) AddMatchTag(tag string) (err error) {
+	e.lock()
+	defer e.unlock()
+	e.matchTag = append(e.matchTag, tag)
+	return
+}
+
+// AddMatchCurrentTag adds a filter for a tag still applying to the device to include in the list, see Device.CurrentTags.
+func (e *Enumerate) AddMatchCurrentTag(tag string) (err error) {
+	e.lock()
+	defer e.unlock()
+	// Current tags are tags as well, so only the tagged devices need to be scanned
+	e.matchTag = append(e.matchTag, tag)
+	e.matchCurrentTag = append(e.matchCurrentTag, tag)
+	return
+}
+
+// addMatchDevtype adds a filter for the device type of the device to include in the list
+func (e *Enumerate) addMatchDevtype(devtype string) {
+	e.lock()
+	defer e.unlock()
+	e.matchDevtype = devtype
+}
+
+// AddMatchParent adds a filter for a parent Device to include in the list.
+func (e *Enumerate) AddMatchParent(parent *Device) (err error) {
+	e.lock()
+	defer e.unlock()
+	if parent == nil {
+		return newError("udev_enumerate_add_match_parent", "", syscall.EINVAL)
+	}
+	e.matchParent = parent
+	return
+}
+
+// AddMatchIsInitialized adds a filter matching only devices which udev has set up already.
+// This makes sure, that the device node permissions and context are properly set and that network devices are fully renamed.
+// Usually, devices which are found in the kernel but not already handled by udev, have still pending events.
+// Services should subscribe to monitor events and wait for these devices to become ready, instead of using uninitialized devices.
+// For now, this will not affect devices which do not have a device node and are not network interfaces.
+func (e *Enumerate) AddMatchIsInitialized() (err error) {
+	e.lock()
+	defer e.unlock()
+	e.matchIsInitialized = true
+	return
+}
+
+// AddSyspath adds a device to the list of enumerated devices, to retrieve it back sorted in dependency order.
+func (e *Enumerate) AddSyspath(syspath string) (err error) {
+	e.lock()
+	defer e.unlock()
+	d, err := e.u.newDeviceFromSyspath(syspath)
+	if err != nil {
+		return newError("udev_enumerate_add_syspath", syspath, err)
+	}
+	e.added[d.syspath] = struct{}{}
+	return
+}
+
+// subsystemMatches checks a subsystem against the subsystem filters.
+func (e *Enumerate) subsystemMatches(subsystem string) bool {
+	for _, p := range e.nomatchSubsystem {
+		if fnmatch(p, subsystem) {
+			return false
+		}
+	}
+	if len(e.matchSubsystem) == 0 {
+		return true
+	}
+	for _, p := range e.matchSubsystem {
+		if fnmatch(p, subsystem) {
+			return true
+		}
+	}
+	return false
+}
+
+// sysnameMatches checks a sysname against the sysname filters.
+func (e *Enumerate) sysnameMatches(sysname string) bool {
+	if len(e.matchSysname) == 0 {
+		return true
+	}
+	for _, p := range e.matchSysname {
+		if fnmatch(p, sysname) {
+			return true
+		}
+	}
+	return false
+}
+
+// sysattrMatches checks if the sys attribute of the device exists and matches the value pattern.
+func sysattrMatches(d *Device, sysattr, value string) bool {
+	v, ok := d.sysattrValue(sysattr)
+	return ok && fnmatch(value, v)
+}
+
+// deviceMatches checks a device against all filters of the enumerate.
+func (e *Enumerate) deviceMatches(d *Device) bool {
+	if !e.subsystemMatches(d.subsystem) || !e.sysnameMatches(d.sysname) {
+		return false
+	}
+	if e.matchIsInitialized && !d.initialized {
+		return false
+	}
+	if e.matchDevtype != "" && d.devtype != e.matchDevtype {
+		return false
+	}
+	if p := e.matchParent; p != nil && d.syspath != p.syspath && !strings.HasPrefix(d.syspath, p.syspath+"/") {
+		return false
+	}
+	// All tags need to be present
+	for _, t := range e.matchTag {
+		if _, ok := d.tags[t]; !ok {
+			return false
+		}
+	}
+	// All current tags need to be present, see Device.CurrentTags
+	for _, t := range e.matchCurrentTag {
+		if !slices.Contains(d.currentTagList(), t) {
+			return false
+		}
+	}
+	// Any property needs to match
+	if len(e.matchProperty) > 0 {
+		matched := false
+		for _, m := range e.matchProperty {
+			for k, v := range d.properties {
+				if fnmatch(m[0], k) && fnmatch(m[1], v) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	// All sys attributes need to match, and none of the excluded ones
+	for _, m := range e.nomatchSysattr {
+		if sysattrMatches(d, m[0], m[1]) {
+			return false
+		}
+	}
+	for _, m := range e.matchSysattr {
+		if !sysattrMatches(d, m[0], m[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// addDevice adds the device at syspath to the list if it matches the filters.
+func (e *Enumerate) addDevice(syspath string) {
+	d, err := e.u.newDeviceFromSyspath(syspath)
+	if err != nil || !e.deviceMatches(d) {
+		return
+	}
+	e.scanned[d.syspath] = struct{}{}
+}
+
+// scanDirAndAddDevices adds the devices in /sys/<basedir>/<subsystem>/<subdir> matching the filters.
+func (e *Enumerate) scanDirAndAddDevices(basedir, subsystem, subdir string) {
+	path := filepath.Join(e.u.sysfs(), basedir, subsystem, subdir)
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, ent := range entries {
+		if strings.HasPrefix(ent.Name(), ".") || !e.sysnameMatches(strings.Replace(ent.Name(), "!", "/", -1)) {
+			continue
+		}
+		e.addDevice(filepath.Join(path, ent.Name()))
+	}
+}
+
+// scanDir scans the subsystem directories in /sys/<basedir> for devices in subdir.
+// If subsystem is empty, the subsystem directory names are matched against the subsystem filters.
+func (e *Enumerate) scanDir(basedir, subdir, subsystem string) error {
+	entries, err := os.ReadDir(filepath.Join(e.u.sysfs(), basedir))
+	if err != nil {
+		return err
+	}
+	for _, ent := range entries {
+		if strings.HasPrefix(ent.Name(), ".") {
+			continue
+		}
+		ss := subsystem
+		if ss == "" {
+			ss = ent.Name()
+		}
+		if !e.subsystemMatches(ss) {
+			continue
+		}
+		e.scanDirAndAddDevices(basedir, ent.Name(), subdir)
+	}
+	return nil
+}
+
+// subsystemDir returns the directory in sysfs listing the subsystems.
+func (u *Udev) subsystemDir() string {
+	if _, err := os.Stat(u.sysfs() + "/subsystem"); err == nil {
+		return "subsystem"
+	}
+	return "bus"
+}
+
+// scanDevices scans sysfs or the udev database for devices matching the filters.
+func (e *Enumerate) scanDevices() error {
+	e.scanned = make(map[string]struct{})
+	switch {
+	case len(e.matchTag) > 0:
+		// Only tagged devices need to be considered
+		for _, t := range e.matchTag {
+			entries, err := os.ReadDir(filepath.Join(e.u.udevRun(), "tags", t))
+			if err != nil {
+				continue
+			}
+			for _, ent := range entries {
+				if d, err := e.u.newDeviceFromDeviceID(ent.Name()); err == nil && e.deviceMatches(d) {
+					e.scanned[d.syspath] = struct{}{}
+				}
+			}
+		}
+		return nil
+	case e.matchParent != nil:
+		// Only the parent and its children need to be considered
+		return filepath.WalkDir(e.matchParent.syspath, func(path string, ent os.DirEntry, err error) error {
+			if err == nil && ent.IsDir() {
+				e.addDevice(path)
+			}
+			return nil
+		})
+	}
+	if dir := e.u.subsystemDir(); dir == "subsystem" {
+		return e.scanDir(dir, "devices", "")
+	}
+	if err := e.scanDir("bus", "devices", ""); err != nil {
+		return err
+	}
+	return e.scanDir("class", "", "")
+}
+
+// scanSubsystems scans sysfs for modules, subsystems and drivers matching the filters.
+func (e *Enumerate) scanSubsystems() error {
+	e.scanned = make(map[string]struct{})
+	if e.subsystemMatches("module") {
+		e.scanDirAndAddDevices("module", "", "")
+	}
+	dir := e.u.subsystemDir()
+	if e.subsystemMatches("subsystem") {
+		e.scanDirAndAddDevices(dir, "", "")
+	}
+	if e.subsystemMatches("drivers") {
+		return e.scanDir(dir, "drivers", "drivers")
+	}
+	return nil
+}
+
+// delayedSyspath reports whether a syspath should be sorted after all others,
+// as its device depends on devices which are not its parents.
+func delayedSyspath(syspath string) bool {
+	return strings.Contains(syspath, "/block/md") || strings.Contains(syspath, "/block/dm-")
+}
+
+// syspathSortKey returns the key used to sort a syspath.
+// The control device of a sound card is sorted after all other devices of the card,
+// as applications rely on it being set up last.
+func syspathSortKey(syspath string) string {
+	if strings.Contains(syspath, "/sound/card") {
+		return strings.Replace(syspath, "/controlC", "/\xffcontrolC", 1)
+	}
+	return syspath
+}
+
+// sortedSyspaths returns the added and scanned syspaths, sorted in dependency order.
+func (e *Enumerate) sortedSyspaths() []string {
+	m := maps.Clone(e.added)
+	maps.Copy(m, e.scanned)
+	s := sortedKeys(m)
+	sort.SliceStable(s, func(i, j int) bool {
+		if di, dj := delayedSyspath(s[i]), delayedSyspath(s[j]); di != dj {
+			return dj
+		}
+		return syspathSortKey(s[i]) < syspathSortKey(s[j])
+	})
+	return s
+}
+
+// DeviceSyspaths retrieves a list of device syspaths matching the filter, sorted in dependency order.
+func (e *Enumerate) DeviceSyspaths() (s []string, err error) {
+	e.lock()
+	defer e.unlock()
+	if err = e.scanDevices(); err != nil {
+		err = newError("udev_enumerate_scan_devices", "", err)
+	} else {
+		s = e.sortedSyspaths()
+	}
+	return
+}
+
+// DeviceSyspathsSeq scans the devices matching the filter and returns an iter.Seq over their syspaths, sorted in dependency order.
+func (e *Enumerate) DeviceSyspathsSeq() (iter.Seq[string], error) {
+	s, err := e.DeviceSyspaths()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Values(s), nil
+}
+
+// SubsystemSyspaths retrieves a list of subsystem syspaths matching the filter, sorted in dependency order.
+func (e *Enumerate) SubsystemSyspaths() (s []string, err error) {
+	e.lock()
+	defer e.unlock()
+	if err = e.scanSubsystems(); err != nil {
+		err = newError("udev_enumerate_scan_subsystems", "", err)
+	} else {
+		s = e.sortedSyspaths()
+	}
+	return
+}
+
+// SubsystemSyspathsSeq scans the subsystems matching the filter and returns an iter.Seq over their syspaths, sorted in dependency order.
+func (e *Enumerate) SubsystemSyspathsSeq() (iter.Seq[string], error) {
+	s, err := e.SubsystemSyspaths()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Values(s), nil
+}
+
+// Devices retrieves a list of Devices matching the filter, sorted in dependency order.
+func (e *Enumerate) Devices() (m []*Device, err error) {
+	s, err := e.DeviceSyspaths()
+	if err != nil {
+		return
+	}
+	m = make([]*Device, 0, len(s))
+	for _, syspath := range s {
+		// Devices may have been removed since scanning
+		if d := e.u.NewDeviceFromSyspath(syspath); d != nil {
+			m = append(m, d)
+		}
+	}
+	return
+}
+
+// DevicesSeq returns an iter.Seq2 over the Devices matching the filter, sorted in dependency order.
+// The devices are scanned when the iteration starts. A failing scan yields a nil Device and the error once,
+// a device which cannot be created, e.g. because it was removed since scanning, yields a nil Device and its error.
+func (e *Enumerate) DevicesSeq() iter.Seq2[*Device, error] {
+	return func(yield func(*Device, error) bool) {
+		s, err := e.DeviceSyspaths()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, syspath := range s {
+			if !yield(e.u.DeviceFromSyspath(syspath)) {
+				return
+			}
+		}
+	}
+}
@@ -4,7 +4,9 @@ package udev
 
 import (
 	"fmt"
+	"os"
 	"runtime"
+	"slices"
 	"testing"
 )
 
@@ -73,7 +75,7 @@ func ExampleEnumerate_Devices() {
 }
 
 func TestEnumerateDevicesWithFilter(t *testing.T) {
-	u := Udev{}
+	u := newTestSysfs(t).udev()
 	e := u.NewEnumerate()
 	e.AddMatchSubsystem("block")
 	e.AddMatchIsInitialized()
@@ -113,6 +115,99 @@ func TestEnumerateDevicesWithFilter(t *testing.T) {
 	}
 }
 
+func TestEnumerateRescan(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	sda := f.path("sys/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda")
+	eth0 := f.path("sys/devices/pci0000:00/0000:00:1f.2/net/eth0")
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("net")
+	if err := e.AddSyspath(sda); err != nil {
+		t.Fatal(err)
+	}
+	s, err := e.DeviceSyspaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s, []string{sda, eth0}) {
+		t.Errorf("DeviceSyspaths() = %v", s)
+	}
+
+	// Scanning the subsystems drops the devices found by scanning the devices, as none is in the net subsystem
+	s, err = e.SubsystemSyspaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s, []string{sda}) {
+		t.Errorf("SubsystemSyspaths() = %v", s)
+	}
+
+	// A rescan only finds the devices still in sysfs, and keeps the added syspaths
+	for _, p := range []string{eth0, f.path("sys/class/net/eth0")} {
+		if err := os.RemoveAll(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err = e.DeviceSyspaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s, []string{sda}) {
+		t.Errorf("DeviceSyspaths() after removing eth0 = %v", s)
+	}
+}
+
+func ExampleEnumerate_DevicesSeq() {
+	// Create Udev and Enumerate
+	u := Udev{}
+	e := u.NewEnumerate()
+
+	// Iterate over the initialized block devices
+	e.AddMatchSubsystem("block")
+	e.AddMatchIsInitialized()
+	for d, err := range e.DevicesSeq() {
+		if err != nil {
+			continue
+		}
+		fmt.Println(d.Syspath())
+	}
+}
+
+func TestEnumerateDevicesSeq(t *testing.T) {
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("mem")
+	dsp, err := e.DeviceSyspaths()
+	if err != nil || len(dsp) == 0 {
+		t.Fail()
+	}
+	seq, err := e.DeviceSyspathsSeq()
+	if err != nil {
+		t.Fail()
+	}
+	i := 0
+	for s := range seq {
+		if i >= len(dsp) || dsp[i] != s {
+			t.Error("Syspaths don't match")
+		}
+		i++
+	}
+	i = 0
+	for d, err := range e.DevicesSeq() {
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if d.Subsystem() != "mem" {
+			t.Error("Wrong subsystem")
+		}
+		i++
+	}
+	if i != len(dsp) {
+		t.Fail()
+	}
+}
+
 func TestEnumerateGC(t *testing.T) {
 	runtime.GC()
 }
@@ -0,0 +1,62 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// ErrOverrun is reported by a monitor when the receive buffer of its socket overflowed and uevents were lost.
+// The state of the devices should be re-enumerated when it is received.
+var ErrOverrun = errors.New("udev: monitor receive buffer overrun, events were lost")
+
+// Error records a failed udev operation, the argument it was called with and the cause of the failure.
+// The cause is usually a syscall.Errno, so errors.Is can be used to check for conditions like fs.ErrNotExist or fs.ErrPermission.
+type Error struct {
+	// Op is the operation which failed, usually the name of the libudev function
+	Op string
+	// Arg is the argument of the operation, like a syspath, subsystem or sysattr, and may be empty
+	Arg string
+	// Err is the underlying error, usually a syscall.Errno, and may be nil if the cause is unknown
+	Err error
+}
+
+func (e *Error) Error() string {
+	s := "udev: " + e.Op
+	if e.Arg != "" {
+		s += " " + e.Arg
+	}
+	if e.Err == nil {
+		return s + " failed"
+	}
+	return s + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports ENODEV and ENXIO, which libudev returns for devices that do not exist, as fs.ErrNotExist
+func (e *Error) Is(target error) bool {
+	return target == fs.ErrNotExist && (e.Err == syscall.ENODEV || e.Err == syscall.ENXIO)
+}
+
+// newError returns a new *Error, unwrapping the syscall.Errno from errors returned by the os package
+func newError(op, arg string, err error) error {
+	var pe *os.PathError
+	var se *os.SyscallError
+	var le *os.LinkError
+	switch {
+	case errors.As(err, &pe):
+		err = pe.Err
+	case errors.As(err, &se):
+		err = se.Err
+	case errors.As(err, &le):
+		err = le.Err
+	}
+	return &Error{Op: op, Arg: arg, Err: err}
+}
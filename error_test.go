@@ -0,0 +1,50 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	var err error = &Error{Op: "udev_device_new_from_syspath", Arg: "/sys/devices/foo", Err: syscall.ENOENT}
+	if err.Error() != "udev: udev_device_new_from_syspath /sys/devices/foo: no such file or directory" {
+		t.Error("Wrong error message:", err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) || !errors.Is(err, syscall.ENOENT) {
+		t.Error("ENOENT should be fs.ErrNotExist")
+	}
+	if !errors.Is(&Error{Op: "udev_device_new_from_devnum", Err: syscall.ENODEV}, fs.ErrNotExist) {
+		t.Error("ENODEV should be fs.ErrNotExist")
+	}
+	if !errors.Is(&Error{Op: "udev_device_set_sysattr_value", Err: syscall.EACCES}, fs.ErrPermission) {
+		t.Error("EACCES should be fs.ErrPermission")
+	}
+	if errors.Is(&Error{Op: "udev_enumerate_scan_devices", Err: syscall.ENOMEM}, fs.ErrNotExist) {
+		t.Error("ENOMEM should not be fs.ErrNotExist")
+	}
+	if (&Error{Op: "udev_enumerate_scan_devices"}).Error() != "udev: udev_enumerate_scan_devices failed" {
+		t.Error("Wrong error message without cause")
+	}
+}
+
+func TestDeviceFromSyspathError(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSyspath("/sys/devices/virtual/mem/nonexistent")
+	if d != nil {
+		t.Error("Device should be nil")
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatal("Error should be an *Error")
+	}
+	if e.Op != "udev_device_new_from_syspath" || e.Arg != "/sys/devices/virtual/mem/nonexistent" {
+		t.Error("Wrong operation or argument:", e)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Error should be fs.ErrNotExist:", err)
+	}
+}
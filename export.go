@@ -0,0 +1,227 @@
+// +build linux
+
+package udev
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MarshalJSON encodes the device number as a "major:minor" string
+func (d Devnum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%d:%d", d.Major(), d.Minor()))
+}
+
+// UnmarshalJSON decodes a device number from a "major:minor" string
+func (d *Devnum) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(s, "%d:%d", &major, &minor); err != nil {
+		return fmt.Errorf("udev: invalid device number %q", s)
+	}
+	*d = MkDev(major, minor)
+	return nil
+}
+
+// MarshalJSON encodes the snapshot with all of its fields, encoding missing lists and maps as empty ones
+// so that the output only depends on the values of the device
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	// The alias type has the fields of a Snapshot without its methods
+	type snapshot Snapshot
+	v := snapshot(s)
+	for _, l := range []*[]string{&v.Tags, &v.CurrentTags, &v.Devlinks} {
+		if *l == nil {
+			*l = []string{}
+		}
+	}
+	for _, m := range []*map[string]string{&v.Properties, &v.Sysattrs} {
+		if *m == nil {
+			*m = map[string]string{}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// ExportEncoder writes snapshots in the format of udevadm info --query=all and --export-db
+type ExportEncoder struct {
+	w *bufio.Writer
+}
+
+// NewExportEncoder returns a pointer to a new encoder writing to w
+func NewExportEncoder(w io.Writer) *ExportEncoder {
+	return &ExportEncoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes a snapshot as a record of P:, N:, L:, S: and E: lines followed by an empty line.
+// The properties are written in sorted order, and the parent of the snapshot is not written.
+func (e *ExportEncoder) Encode(s *Snapshot) error {
+	fmt.Fprintf(e.w, "P: %s\n", s.Devpath)
+	if s.Devnode != "" {
+		fmt.Fprintf(e.w, "N: %s\n", strings.TrimPrefix(s.Devnode, "/dev/"))
+	}
+	fmt.Fprintf(e.w, "L: %d\n", s.DevlinkPriority)
+	for _, l := range s.Devlinks {
+		fmt.Fprintf(e.w, "S: %s\n", strings.TrimPrefix(l, "/dev/"))
+	}
+	for _, k := range sortedMapKeys(s.Properties) {
+		fmt.Fprintf(e.w, "E: %s=%s\n", k, s.Properties[k])
+	}
+	e.w.WriteString("\n")
+	return e.w.Flush()
+}
+
+// ExportDecoder reads snapshots from the output of udevadm info --query=all or --export-db
+type ExportDecoder struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewExportDecoder returns a pointer to a new decoder reading from r
+func NewExportDecoder(r io.Reader) *ExportDecoder {
+	s := bufio.NewScanner(r)
+	// Properties may be long, e.g. DEVLINKS of disks with many links
+	s.Buffer(nil, 1024*1024)
+	return &ExportDecoder{s: s}
+}
+
+// Decode reads the next record and returns its snapshot, or io.EOF after the last record.
+// Besides the P:, N:, L:, S: and E: lines, the M:, R:, U:, T:, D:, V:, G: and Q: lines printed
+// by newer versions of udevadm are read, and other lines are ignored. The snapshot has no parent
+// and no sys attributes, and is initialized if it has a USEC_INITIALIZED property.
+func (d *ExportDecoder) Decode() (*Snapshot, error) {
+	var r Record
+	var s Snapshot
+	var devnode, devnum string
+	var tags, current []string
+	found := false
+	for d.s.Scan() {
+		d.line++
+		line := d.s.Text()
+		if line == "" {
+			if found {
+				break
+			}
+			// Skip the empty lines before a record
+			continue
+		}
+		if len(line) < 3 || line[1] != ':' || line[2] != ' ' {
+			return nil, d.errorf("invalid line %q", line)
+		}
+		if !found && line[0] != 'P' {
+			return nil, d.errorf("record does not start with P:")
+		}
+		found = true
+		value := line[3:]
+		switch line[0] {
+		case 'P':
+			if s.Devpath != "" {
+				return nil, d.errorf("record with two P: lines")
+			}
+			s.Devpath = value
+		case 'M':
+			s.Sysname = value
+		case 'R':
+			s.Sysnum = value
+		case 'U':
+			s.Subsystem = value
+		case 'T':
+			s.Devtype = value
+		case 'D':
+			// The type of the device node, followed by major:minor
+			if i := strings.IndexByte(value, ' '); i > 0 {
+				devnum = value[i+1:]
+			}
+		case 'V':
+			s.Driver = value
+		case 'N':
+			devnode = "/dev/" + value
+		case 'L':
+			p, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, d.errorf("invalid devlink priority %q", value)
+			}
+			s.DevlinkPriority = p
+		case 'S':
+			s.Devlinks = append(s.Devlinks, "/dev/"+value)
+		case 'G':
+			tags = append(tags, value)
+		case 'Q':
+			current = append(current, value)
+		case 'E':
+			i := strings.IndexByte(value, '=')
+			if i <= 0 {
+				return nil, d.errorf("invalid property %q", value)
+			}
+			if s.Properties == nil {
+				s.Properties = make(map[string]string)
+			}
+			s.Properties[value[:i]] = value[i+1:]
+		}
+	}
+	if err := d.s.Err(); err != nil {
+		return nil, &Error{Op: "ExportDecoder.Decode", Arg: fmt.Sprintf("line %d", d.line), Err: err}
+	}
+	if !found {
+		return nil, io.EOF
+	}
+
+	// Fields without a line of their own are derived from the properties, like for a uevent
+	r.Syspath = "/sys" + s.Devpath
+	r.Properties = s.Properties
+	s.Syspath = r.Syspath
+	if s.Sysname == "" {
+		s.Sysname = r.sysname()
+	}
+	if s.Sysnum == "" {
+		s.Sysnum = r.sysnum()
+	}
+	for _, f := range []struct {
+		field *string
+		key   string
+	}{{&s.Subsystem, "SUBSYSTEM"}, {&s.Devtype, "DEVTYPE"}, {&s.Driver, "DRIVER"}, {&s.Action, "ACTION"}} {
+		if *f.field == "" {
+			*f.field = r.Properties[f.key]
+		}
+	}
+	if s.Devnode = devnode; s.Devnode == "" {
+		s.Devnode = r.devnode()
+	}
+	if s.Devnum = r.devnum(); devnum != "" {
+		var major, minor int
+		if _, err := fmt.Sscanf(devnum, "%d:%d", &major, &minor); err == nil {
+			s.Devnum = MkDev(major, minor)
+		}
+	}
+	s.Seqnum, _ = strconv.ParseUint(r.Properties["SEQNUM"], 10, 64)
+	_, s.Initialized = r.Properties["USEC_INITIALIZED"]
+	if s.Devlinks == nil {
+		s.Devlinks = r.list("DEVLINKS")
+	}
+	slices.Sort(s.Devlinks)
+	if s.Tags = tags; s.Tags == nil {
+		s.Tags = r.list("TAGS")
+	}
+	slices.Sort(s.Tags)
+	if s.CurrentTags = current; s.CurrentTags == nil {
+		s.CurrentTags = currentTags(s.Properties, s.Tags)
+	}
+	slices.Sort(s.CurrentTags)
+	if s.Properties == nil {
+		s.Properties = make(map[string]string)
+	}
+	s.Sysattrs = make(map[string]string)
+	return &s, nil
+}
+
+// errorf returns an *Error for a malformed record at the current line
+func (d *ExportDecoder) errorf(format string, args ...interface{}) error {
+	return &Error{Op: "ExportDecoder.Decode", Arg: fmt.Sprintf("line %d", d.line), Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,181 @@
+// +build linux
+
+package udev
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func ExampleExportEncoder() {
+
+	// Create Udev and Enumerate
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("block")
+
+	// Write the devices like udevadm info --export-db
+	enc := NewExportEncoder(os.Stdout)
+	devices, _ := e.Devices()
+	for _, d := range devices {
+		s := d.Snapshot()
+		enc.Encode(&s)
+	}
+}
+
+const exportSample = `P: /devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1
+M: sda1
+R: 1
+U: block
+T: partition
+D: b 8:1
+N: sda1
+L: 0
+S: disk/by-uuid/1234
+S: disk/by-id/ata-disk0-part1
+Q: systemd
+G: systemd
+G: uaccess
+E: DEVPATH=/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1
+E: SUBSYSTEM=block
+E: DEVNAME=/dev/sda1
+E: DEVTYPE=partition
+E: MAJOR=8
+E: MINOR=1
+E: USEC_INITIALIZED=1000
+E: ID_FS_TYPE=ext4
+
+P: /devices/virtual/mem/null
+N: null
+L: 0
+E: DEVPATH=/devices/virtual/mem/null
+E: SUBSYSTEM=mem
+E: DEVNAME=/dev/null
+E: MAJOR=1
+E: MINOR=3
+`
+
+func TestExportDecoder(t *testing.T) {
+	dec := NewExportDecoder(strings.NewReader("\n" + exportSample))
+	s, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Syspath != "/sys/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1" || s.Sysname != "sda1" || s.Sysnum != "1" {
+		t.Error("Wrong identity", s.Syspath, s.Sysname, s.Sysnum)
+	}
+	if s.Subsystem != "block" || s.Devtype != "partition" || s.Devnode != "/dev/sda1" || s.Devnum != MkDev(8, 1) || !s.Initialized {
+		t.Error("Wrong device", s.Subsystem, s.Devtype, s.Devnode, s.Devnum)
+	}
+	if fmt.Sprint(s.Devlinks) != "[/dev/disk/by-id/ata-disk0-part1 /dev/disk/by-uuid/1234]" || fmt.Sprint(s.Tags) != "[systemd uaccess]" || fmt.Sprint(s.CurrentTags) != "[systemd]" {
+		t.Error("Wrong lists", s.Devlinks, s.Tags, s.CurrentTags)
+	}
+	if len(s.Properties) != 8 || s.Properties["ID_FS_TYPE"] != "ext4" {
+		t.Error("Wrong properties", s.Properties)
+	}
+	s, err = dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Sysname != "null" || s.Subsystem != "mem" || s.Devnum != MkDev(1, 3) || s.Initialized || len(s.Tags) != 0 {
+		t.Error("Wrong device", s)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Error(err)
+	}
+	for _, invalid := range []string{"E: A=b\n", "P: /devices/a\nX\n", "P: /devices/a\nE: =b\n", "P: /devices/a\nL: x\n", "P: /devices/a\nP: /devices/b\n"} {
+		if _, err := NewExportDecoder(strings.NewReader(invalid)).Decode(); err == nil || err == io.EOF {
+			t.Errorf("No error for %q", invalid)
+		}
+	}
+}
+
+func TestExportEncoder(t *testing.T) {
+	// Records in the classic format are written back unchanged, except for the order of the properties and device links
+	classic := `P: /devices/virtual/block/loop0
+N: loop0
+L: -10
+S: disk/by-id/a
+S: disk/by-id/b
+E: DEVNAME=/dev/loop0
+E: DEVPATH=/devices/virtual/block/loop0
+E: MAJOR=7
+E: MINOR=0
+E: SUBSYSTEM=block
+
+P: /devices/virtual/net/lo
+L: 0
+E: DEVPATH=/devices/virtual/net/lo
+E: IFINDEX=1
+E: INTERFACE=lo
+E: SUBSYSTEM=net
+
+`
+	dec := NewExportDecoder(strings.NewReader(classic))
+	var b bytes.Buffer
+	enc := NewExportEncoder(&b)
+	for {
+		s, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.String() != classic {
+		t.Error(b.String())
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.Snapshot()
+	var b bytes.Buffer
+	if err := NewExportEncoder(&b).Encode(&s); err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewExportDecoder(&b).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Equal(o) {
+		t.Errorf("%+v decoded as %+v", s, o)
+	}
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	s := Snapshot{Syspath: "/sys/devices/virtual/mem/null", Devnum: MkDev(1, 3)}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"syspath":"/sys/devices/virtual/mem/null","devpath":"","subsystem":"","devtype":"","sysname":"","sysnum":"",` +
+		`"devnode":"","driver":"","devnum":"1:3","action":"","seqnum":0,"initialized":false,"devlinkPriority":0,` +
+		`"properties":{},"tags":[],"currentTags":[],"devlinks":[],"sysattrs":{}}`
+	if string(b) != want {
+		t.Error(string(b))
+	}
+	var o Snapshot
+	if err := json.Unmarshal(b, &o); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Equal(&o) || o.Devnum != MkDev(1, 3) {
+		t.Error("Snapshot not decoded", o)
+	}
+	if err := json.Unmarshal([]byte(`"1"`), &o.Devnum); err == nil {
+		t.Error("Invalid device number decoded")
+	}
+}
@@ -0,0 +1,193 @@
+// +build linux
+
+package udev
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DeviceSource is a source of devices, either the live system through a Udev or a capture of it in an ExportDB.
+// Code written against a DeviceSource runs unchanged on both.
+type DeviceSource interface {
+	// MatchDevices returns the devices selected by the filter
+	MatchDevices(f Filter) ([]DeviceInfo, error)
+	// DeviceInfoFromSyspath returns the device with the syspath given
+	DeviceInfoFromSyspath(syspath string) (DeviceInfo, error)
+	// DeviceInfoFromDevnum returns the device with the type ('c' or 'b') and number given
+	DeviceInfoFromDevnum(deviceType uint8, n Devnum) (DeviceInfo, error)
+	// DeviceInfoFromSubsystemSysname returns the device with the subsystem and sysname given
+	DeviceInfoFromSubsystemSysname(subsystem, sysname string) (DeviceInfo, error)
+	// DeviceInfoFromDeviceID returns the device with the device id given, see Udev.DeviceFromDeviceID
+	DeviceInfoFromDeviceID(id string) (DeviceInfo, error)
+}
+
+var (
+	_ DeviceSource = (*Udev)(nil)
+	_ DeviceSource = (*ExportDB)(nil)
+)
+
+// MatchDevices enumerates the devices selected by the filter
+func (u *Udev) MatchDevices(f Filter) ([]DeviceInfo, error) {
+	e := u.NewEnumerate()
+	if err := f.Apply(e); err != nil {
+		return nil, err
+	}
+	devices, err := e.Devices()
+	if err != nil {
+		return nil, err
+	}
+	var r []DeviceInfo
+	for _, d := range devices {
+		// Not all matches of the filter can be applied to the enumerate exactly
+		if f.Match(d) {
+			r = append(r, d)
+		}
+	}
+	return r, nil
+}
+
+// DeviceInfoFromSyspath is like DeviceFromSyspath, returning a DeviceInfo
+func (u *Udev) DeviceInfoFromSyspath(syspath string) (DeviceInfo, error) {
+	return deviceInfoOrError(u.DeviceFromSyspath(syspath))
+}
+
+// DeviceInfoFromDevnum is like DeviceFromDevnum, returning a DeviceInfo
+func (u *Udev) DeviceInfoFromDevnum(deviceType uint8, n Devnum) (DeviceInfo, error) {
+	return deviceInfoOrError(u.DeviceFromDevnum(deviceType, n))
+}
+
+// DeviceInfoFromSubsystemSysname is like DeviceFromSubsystemSysname, returning a DeviceInfo
+func (u *Udev) DeviceInfoFromSubsystemSysname(subsystem, sysname string) (DeviceInfo, error) {
+	return deviceInfoOrError(u.DeviceFromSubsystemSysname(subsystem, sysname))
+}
+
+// DeviceInfoFromDeviceID is like DeviceFromDeviceID, returning a DeviceInfo
+func (u *Udev) DeviceInfoFromDeviceID(id string) (DeviceInfo, error) {
+	return deviceInfoOrError(u.DeviceFromDeviceID(id))
+}
+
+// deviceInfoOrError returns the device as a DeviceInfo, which is nil rather than a nil *Device on error
+func deviceInfoOrError(d *Device, err error) (DeviceInfo, error) {
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ExportDB is an offline source of devices read from the output of udevadm info --export-db,
+// e.g. as attached to a bug report. The devices are in-memory devices without sys attributes,
+// and the parent of a device is the closest device above it in the dump.
+type ExportDB struct {
+	devices   []*MemDevice
+	bySyspath map[string]*MemDevice
+}
+
+// ReadExportDB reads all records from r, and returns a pointer to a new ExportDB holding their devices
+func ReadExportDB(r io.Reader) (*ExportDB, error) {
+	db := &ExportDB{bySyspath: make(map[string]*MemDevice)}
+	dec := NewExportDecoder(r)
+	for {
+		s, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		m := NewMemDeviceFromSnapshot(s)
+		db.devices = append(db.devices, m)
+		db.bySyspath[m.Syspath()] = m
+	}
+	// Link the devices to their parents, which may come after them in the dump
+	for _, m := range db.devices {
+		for p := path.Dir(m.Devpath()); p != "/" && p != "."; p = path.Dir(p) {
+			if parent, ok := db.bySyspath["/sys"+p]; ok {
+				m.parent = parent
+				break
+			}
+		}
+	}
+	return db, nil
+}
+
+// Devices returns all devices, in the order of the dump
+func (db *ExportDB) Devices() []*MemDevice {
+	return append([]*MemDevice(nil), db.devices...)
+}
+
+// MatchDevices returns the devices selected by the filter, in the order of the dump
+func (db *ExportDB) MatchDevices(f Filter) ([]DeviceInfo, error) {
+	var r []DeviceInfo
+	for _, m := range db.devices {
+		if f.Match(m) {
+			r = append(r, m)
+		}
+	}
+	return r, nil
+}
+
+// DeviceInfoFromSyspath returns the device with the syspath given
+func (db *ExportDB) DeviceInfoFromSyspath(syspath string) (DeviceInfo, error) {
+	if m, ok := db.bySyspath[syspath]; ok {
+		return m, nil
+	}
+	return nil, newError("udev_device_new_from_syspath", syspath, syscall.ENODEV)
+}
+
+// DeviceInfoFromDevnum returns the device with the type ('c' or 'b') and number given
+func (db *ExportDB) DeviceInfoFromDevnum(deviceType uint8, n Devnum) (DeviceInfo, error) {
+	if deviceType == 'b' || deviceType == 'c' {
+		for _, m := range db.devices {
+			if m.Devnum() == n && n.Major() > 0 && (m.Subsystem() == "block") == (deviceType == 'b') {
+				return m, nil
+			}
+		}
+	}
+	return nil, newError("udev_device_new_from_devnum", fmt.Sprintf("%c%d:%d", deviceType, n.Major(), n.Minor()), syscall.ENODEV)
+}
+
+// DeviceInfoFromSubsystemSysname returns the device with the subsystem and sysname given
+func (db *ExportDB) DeviceInfoFromSubsystemSysname(subsystem, sysname string) (DeviceInfo, error) {
+	for _, m := range db.devices {
+		if m.Subsystem() == subsystem && m.Sysname() == sysname {
+			return m, nil
+		}
+	}
+	return nil, newError("udev_device_new_from_subsystem_sysname", subsystem+":"+sysname, syscall.ENODEV)
+}
+
+// DeviceInfoFromDeviceID returns the device with the device id given:
+// b8:0 (block device), c1:3 (character device), n3 (network interface) or +pci:0000:00:1f.2 (subsystem and sysname).
+func (db *ExportDB) DeviceInfoFromDeviceID(id string) (DeviceInfo, error) {
+	if len(id) >= 2 {
+		switch id[0] {
+		case 'b', 'c':
+			var major, minor int
+			if _, err := fmt.Sscanf(id[1:], "%d:%d", &major, &minor); err == nil {
+				if m, err := db.DeviceInfoFromDevnum(id[0], MkDev(major, minor)); err == nil {
+					return m, nil
+				}
+			}
+		case 'n':
+			if ifindex, err := strconv.Atoi(id[1:]); err == nil && ifindex > 0 {
+				for _, m := range db.devices {
+					if m.PropertyValue("IFINDEX") == id[1:] {
+						return m, nil
+					}
+				}
+			}
+		case '+':
+			if i := strings.IndexByte(id, ':'); i > 1 {
+				if m, err := db.DeviceInfoFromSubsystemSysname(id[1:i], id[i+1:]); err == nil {
+					return m, nil
+				}
+			}
+		}
+	}
+	return nil, newError("udev_device_new_from_device_id", id, syscall.ENODEV)
+}
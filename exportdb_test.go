@@ -0,0 +1,189 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+// partitions is code under test, running on a live system and on captures alike
+func partitions(src DeviceSource, fsType string) ([]string, error) {
+	devices, err := src.MatchDevices(Filter{
+		Subsystems: []string{"block"},
+		Devtype:    "partition",
+		Properties: map[string]string{"ID_FS_TYPE": fsType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var r []string
+	for _, d := range devices {
+		r = append(r, d.Devnode())
+	}
+	return r, nil
+}
+
+func ExampleReadExportDB() {
+
+	// Read the output of udevadm info --export-db from a bug report
+	f, _ := os.Open("export-db.txt")
+	defer f.Close()
+	db, _ := ReadExportDB(f)
+
+	// Run the same code as on the live system
+	u := Udev{}
+	for _, src := range []DeviceSource{&u, db} {
+		fmt.Println(partitions(src, "ext4"))
+	}
+}
+
+const exportDBSample = `P: /devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1
+N: sda1
+L: 0
+S: disk/by-uuid/1234
+E: DEVPATH=/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1
+E: SUBSYSTEM=block
+E: DEVNAME=/dev/sda1
+E: DEVTYPE=partition
+E: MAJOR=8
+E: MINOR=1
+E: ID_FS_TYPE=ext4
+E: USEC_INITIALIZED=1000
+
+P: /devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda
+N: sda
+L: 0
+E: DEVPATH=/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda
+E: SUBSYSTEM=block
+E: DEVNAME=/dev/sda
+E: DEVTYPE=disk
+E: MAJOR=8
+E: MINOR=0
+E: TAGS=:systemd:
+E: USEC_INITIALIZED=1000
+
+P: /devices/pci0000:00/0000:00:1f.2
+L: 0
+E: DEVPATH=/devices/pci0000:00/0000:00:1f.2
+E: SUBSYSTEM=pci
+E: DRIVER=ahci
+E: PCI_ID=8086:2922
+
+P: /devices/pci0000:00/0000:00:1f.2/net/eth0
+L: 0
+E: DEVPATH=/devices/pci0000:00/0000:00:1f.2/net/eth0
+E: SUBSYSTEM=net
+E: INTERFACE=eth0
+E: IFINDEX=2
+
+P: /devices/virtual/mem/null
+N: null
+L: 0
+E: DEVPATH=/devices/virtual/mem/null
+E: SUBSYSTEM=mem
+E: DEVNAME=/dev/null
+E: MAJOR=1
+E: MINOR=3
+`
+
+func TestExportDB(t *testing.T) {
+	db, err := ReadExportDB(strings.NewReader(exportDBSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Devices()) != 5 {
+		t.Fatal("Wrong number of devices", len(db.Devices()))
+	}
+	if p, err := partitions(db, "ext4"); err != nil || fmt.Sprint(p) != "[/dev/sda1]" {
+		t.Error("Wrong partitions", p, err)
+	}
+	// Parents may follow their children in the dump, and directories without a device are skipped
+	sda1, err := db.DeviceInfoFromSyspath("/sys/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sda := sda1.ParentInfo()
+	if sda == nil || sda.Sysname() != "sda" || !sda.HasTag("systemd") {
+		t.Fatal("Wrong parent")
+	}
+	pci := sda.ParentInfo()
+	if pci == nil || pci.Driver() != "ahci" || pci.ParentInfo() != nil {
+		t.Fatal("Wrong grand parent")
+	}
+	tests := []struct {
+		f        Filter
+		sysnames string
+	}{
+		{Filter{}, "[sda1 sda 0000:00:1f.2 eth0 null]"},
+		{Filter{Subsystems: []string{"block"}, Devtype: "disk"}, "[sda]"},
+		{Filter{Tags: []string{"systemd"}}, "[sda]"},
+		{Filter{CurrentTags: []string{"systemd"}}, "[sda]"},
+		{Filter{Properties: map[string]string{"PCI_ID": "8086:*"}}, "[0000:00:1f.2]"},
+		{Filter{Sysnames: []string{"e*", "n*"}}, "[eth0 null]"},
+		{Filter{IsInitialized: true}, "[sda1 sda]"},
+		// Dumps have no sys attributes
+		{Filter{Sysattrs: map[string]string{"size": "*"}}, "[]"},
+	}
+	for i, test := range tests {
+		devices, err := db.MatchDevices(test.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sysnames []string
+		for _, d := range devices {
+			sysnames = append(sysnames, d.Sysname())
+		}
+		if fmt.Sprint(sysnames) != test.sysnames {
+			t.Errorf("Filter %d matched %v", i, sysnames)
+		}
+	}
+	// Parents are matched by syspath, whether they are from the dump or not
+	for _, parent := range []DeviceInfo{sda, NewMemDevice(nil, map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:1f.2"}, nil)} {
+		devices, err := db.MatchDevices(Filter{Parent: parent, Subsystems: []string{"block"}})
+		if err != nil || len(devices) != 2 || devices[0] != sda1 || devices[1] != sda {
+			t.Error("Wrong devices below", parent.Syspath(), devices, err)
+		}
+	}
+	for _, lookup := range []func() (DeviceInfo, error){
+		func() (DeviceInfo, error) { return db.DeviceInfoFromDevnum('b', MkDev(8, 0)) },
+		func() (DeviceInfo, error) { return db.DeviceInfoFromSubsystemSysname("block", "sda") },
+		func() (DeviceInfo, error) { return db.DeviceInfoFromDeviceID("b8:0") },
+	} {
+		if d, err := lookup(); err != nil || d.Sysname() != "sda" {
+			t.Error("Lookup failed", err)
+		}
+	}
+	if d, err := db.DeviceInfoFromDeviceID("n2"); err != nil || d.Sysname() != "eth0" {
+		t.Error("Lookup by ifindex failed", err)
+	}
+	if d, err := db.DeviceInfoFromDeviceID("+pci:0000:00:1f.2"); err != nil || d.Subsystem() != "pci" {
+		t.Error("Lookup by subsystem and sysname failed", err)
+	}
+	for _, id := range []string{"c8:0", "b1:3", "n3", "+mem:zero", "x"} {
+		if d, err := db.DeviceInfoFromDeviceID(id); d != nil || !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Device %s found", id)
+		}
+	}
+}
+
+func TestUdevDeviceSource(t *testing.T) {
+	u := Udev{}
+	devices, err := u.MatchDevices(Filter{Subsystems: []string{"mem"}, Sysnames: []string{"null"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].Devnode() != "/dev/null" {
+		t.Error("Wrong devices", devices)
+	}
+	if d, err := u.DeviceInfoFromSubsystemSysname("mem", "missing"); d != nil || err == nil {
+		t.Error("Missing device found")
+	}
+	if d, err := u.DeviceInfoFromDevnum('c', MkDev(1, 3)); err != nil || d.Sysname() != "null" {
+		t.Error("Device not found", err)
+	}
+}
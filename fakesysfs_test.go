@@ -0,0 +1,212 @@
+// +build linux
+
+package udev
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeSysfs is a synthetic sysfs tree and udev database in a temporary directory, read by the pure Go backend.
+// Tests using it are skipped with libudev, and run with go test -tags purego.
+type fakeSysfs struct {
+	t    *testing.T
+	root string
+}
+
+// fakeDevice describes a device added to a fakeSysfs
+type fakeDevice struct {
+	devpath   string
+	subsystem string
+	// bus places the device in /sys/bus/<subsystem>/devices instead of /sys/class/<subsystem>
+	bus     bool
+	devtype string
+	driver  string
+	devname string
+	devnum  Devnum
+	ifindex int
+	// Sys attributes, written as files next to the uevent file
+	sysattrs map[string]string
+	// Database entry of the device, which is only written if initialized is set
+	initialized bool
+	properties  map[string]string
+	tags        []string
+	currentTags []string
+	devlinks    []string
+}
+
+// newFakeSysfs creates an empty sysfs tree and udev database, removed when the test ends
+func newFakeSysfs(t *testing.T) *fakeSysfs {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSysfs{t: t, root: root}
+	for _, dir := range []string{"sys/devices", "sys/bus", "sys/class", "sys/dev/block", "sys/dev/char", "run/udev/data", "run/udev/tags"} {
+		f.mkdir(dir)
+	}
+	return f
+}
+
+// path returns the absolute path of a path relative to the root of the tree
+func (f *fakeSysfs) path(rel string) string {
+	return filepath.Join(f.root, rel)
+}
+
+func (f *fakeSysfs) mkdir(rel string) {
+	if err := os.MkdirAll(f.path(rel), 0755); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *fakeSysfs) write(rel, content string) {
+	f.mkdir(filepath.Dir(rel))
+	if err := os.WriteFile(f.path(rel), []byte(content), 0644); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *fakeSysfs) symlink(rel, target string) {
+	f.mkdir(filepath.Dir(rel))
+	if err := os.Symlink(f.path(target), f.path(rel)); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// add adds a device to the tree and the udev database, and returns its syspath
+func (f *fakeSysfs) add(d fakeDevice) string {
+	dir := "sys" + d.devpath
+	name := filepath.Base(d.devpath)
+	f.mkdir(dir)
+
+	// The subsystem link, and the link to the device from its subsystem
+	if d.subsystem != "" {
+		ssdir := "sys/class/" + d.subsystem
+		link := ssdir + "/" + name
+		if d.bus {
+			ssdir = "sys/bus/" + d.subsystem
+			link = ssdir + "/devices/" + name
+		}
+		f.mkdir(ssdir)
+		f.symlink(dir+"/subsystem", ssdir)
+		f.symlink(link, dir)
+		if d.driver != "" {
+			f.mkdir("sys/bus/" + d.subsystem + "/drivers/" + d.driver)
+			f.symlink(dir+"/driver", "sys/bus/"+d.subsystem+"/drivers/"+d.driver)
+		}
+	}
+
+	// The uevent file
+	var uevent []string
+	if d.devtype != "" {
+		uevent = append(uevent, "DEVTYPE="+d.devtype)
+	}
+	var id string
+	if d.devnum.Major() > 0 {
+		uevent = append(uevent, fmt.Sprintf("MAJOR=%d", d.devnum.Major()), fmt.Sprintf("MINOR=%d", d.devnum.Minor()))
+		typ, t := "char", "c"
+		if d.subsystem == "block" {
+			typ, t = "block", "b"
+		}
+		f.symlink(fmt.Sprintf("sys/dev/%s/%d:%d", typ, d.devnum.Major(), d.devnum.Minor()), dir)
+		id = fmt.Sprintf("%s%d:%d", t, d.devnum.Major(), d.devnum.Minor())
+	}
+	if d.devname != "" {
+		uevent = append(uevent, "DEVNAME="+d.devname)
+	}
+	if d.ifindex > 0 {
+		uevent = append(uevent, "INTERFACE="+name, fmt.Sprintf("IFINDEX=%d", d.ifindex))
+		f.write(dir+"/ifindex", fmt.Sprintf("%d\n", d.ifindex))
+		id = fmt.Sprintf("n%d", d.ifindex)
+	}
+	if id == "" {
+		id = "+" + d.subsystem + ":" + name
+	}
+	f.write(dir+"/uevent", strings.Join(uevent, "\n")+"\n")
+
+	for k, v := range d.sysattrs {
+		f.write(dir+"/"+k, v+"\n")
+	}
+
+	// The database entry, and the tag links pointing to it
+	if d.initialized {
+		db := []string{"I:1000"}
+		for _, l := range d.devlinks {
+			db = append(db, "S:"+strings.TrimPrefix(l, "/dev/"))
+		}
+		keys := make([]string, 0, len(d.properties))
+		for k := range d.properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			db = append(db, "E:"+k+"="+d.properties[k])
+		}
+		for _, t := range d.tags {
+			db = append(db, "G:"+t)
+			f.write("run/udev/tags/"+t+"/"+id, "")
+		}
+		for _, t := range d.currentTags {
+			db = append(db, "Q:"+t)
+		}
+		f.write("run/udev/data/"+id, strings.Join(db, "\n")+"\n")
+	}
+	return f.path(dir)
+}
+
+// newTestSysfs returns a fake sysfs tree with a PCI controller, a disk with a partition, a network interface and a virtual device
+func newTestSysfs(t *testing.T) *fakeSysfs {
+	f := newFakeSysfs(t)
+	pci := "/devices/pci0000:00/0000:00:1f.2"
+	f.add(fakeDevice{
+		devpath:   pci,
+		subsystem: "pci",
+		bus:       true,
+		driver:    "ahci",
+		sysattrs:  map[string]string{"vendor": "0x8086", "device": "0x2922"},
+	})
+	f.add(fakeDevice{
+		devpath:     pci + "/ata1/host0/target0:0:0/0:0:0:0/block/sda",
+		subsystem:   "block",
+		devtype:     "disk",
+		devname:     "sda",
+		devnum:      MkDev(8, 0),
+		sysattrs:    map[string]string{"size": "2048", "removable": "0", "ro": "0", "queue/logical_block_size": "512", "queue/rotational": "1"},
+		initialized: true,
+		properties:  map[string]string{"ID_BUS": "ata", "ID_TYPE": "disk", "ID_SERIAL": "disk0"},
+		tags:        []string{"systemd"},
+		devlinks:    []string{"/dev/disk/by-id/ata-disk0"},
+	})
+	f.add(fakeDevice{
+		devpath:     pci + "/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1",
+		subsystem:   "block",
+		devtype:     "partition",
+		devname:     "sda1",
+		devnum:      MkDev(8, 1),
+		sysattrs:    map[string]string{"size": "1024", "partition": "1"},
+		initialized: true,
+		properties:  map[string]string{"ID_BUS": "ata", "ID_TYPE": "disk", "ID_FS_TYPE": "ext4", "ID_FS_UUID": "1234", "ID_PART_ENTRY_TYPE": "0x83"},
+		tags:        []string{"systemd", "uaccess"},
+		currentTags: []string{"systemd"},
+		devlinks:    []string{"/dev/disk/by-id/ata-disk0-part1", "/dev/disk/by-uuid/1234"},
+	})
+	f.add(fakeDevice{
+		devpath:     pci + "/net/eth0",
+		subsystem:   "net",
+		ifindex:     2,
+		sysattrs:    map[string]string{"address": "52:54:00:12:34:56", "operstate": "up", "mtu": "1500", "carrier": "1", "speed": "-1"},
+		initialized: true,
+		properties:  map[string]string{"ID_NET_NAME_PATH": "enp0s31f2"},
+	})
+	f.add(fakeDevice{
+		devpath:   "/devices/virtual/mem/null",
+		subsystem: "mem",
+		devname:   "null",
+		devnum:    MkDev(1, 3),
+	})
+	return f
+}
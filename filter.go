@@ -0,0 +1,299 @@
+// +build linux
+
+package udev
+
+import (
+	"sort"
+	"strings"
+)
+
+// Filter is a declarative set of matches selecting devices.
+// The same Filter can be applied to an Enumerate, installed on a Monitor and evaluated against a Device with Match.
+// Patterns are shell glob patterns as in fnmatch(3), where '*' also matches '/'.
+// The zero Filter matches all devices.
+type Filter struct {
+	// Subsystems matches devices of any of the subsystem patterns, or of any subsystem if empty
+	Subsystems []string
+	// NomatchSubsystems excludes devices of any of the subsystem patterns
+	NomatchSubsystems []string
+	// Devtype matches devices of the device type, or of any device type if empty
+	Devtype string
+	// Sysnames matches devices with any of the sysname patterns, or with any sysname if empty
+	Sysnames []string
+	// Properties matches devices having any of the properties, both name and value being patterns
+	Properties map[string]string
+	// Sysattrs matches devices having all of the sys attributes, with the values matching the patterns
+	Sysattrs map[string]string
+	// NomatchSysattrs excludes devices having any of the sys attributes with the value matching the pattern
+	NomatchSysattrs map[string]string
+	// Tags matches devices having all of the tags
+	Tags []string
+	// CurrentTags matches devices having all of the tags as current tags, see Device.CurrentTags
+	CurrentTags []string
+	// Parent matches the parent device and the devices below it, if not nil.
+	// Any DeviceInfo can be the parent, as devices are matched by syspath.
+	Parent DeviceInfo
+	// IsInitialized matches only devices which udev has set up already
+	IsInitialized bool
+}
+
+// Match reports whether the device is selected by the filter.
+// Any DeviceInfo can be matched, like the in-memory devices of an ExportDB.
+func (f *Filter) Match(d DeviceInfo) bool {
+	return f.match(d, true)
+}
+
+// match reports whether the device is selected by the filter, ignoring the sys attributes unless sysattrs is set
+func (f *Filter) match(d DeviceInfo, sysattrs bool) bool {
+	subsystem := d.Subsystem()
+	if anyMatch(f.NomatchSubsystems, subsystem) {
+		return false
+	}
+	if len(f.Subsystems) > 0 && !anyMatch(f.Subsystems, subsystem) {
+		return false
+	}
+	if f.Devtype != "" && d.Devtype() != f.Devtype {
+		return false
+	}
+	if len(f.Sysnames) > 0 && !anyMatch(f.Sysnames, d.Sysname()) {
+		return false
+	}
+	if p := f.Parent; p != nil {
+		syspath, parent := d.Syspath(), p.Syspath()
+		if syspath != parent && !strings.HasPrefix(syspath, parent+"/") {
+			return false
+		}
+	}
+	if f.IsInitialized && !d.IsInitialized() {
+		return false
+	}
+	for _, t := range f.Tags {
+		if !d.HasTag(t) {
+			return false
+		}
+	}
+	for _, t := range f.CurrentTags {
+		if !d.HasCurrentTag(t) {
+			return false
+		}
+	}
+	if len(f.Properties) > 0 {
+		matched := false
+		for k, v := range d.Properties() {
+			for pk, pv := range f.Properties {
+				if fnmatch(pk, k) && fnmatch(pv, v) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if !sysattrs {
+		return true
+	}
+	for sysattr, pattern := range f.NomatchSysattrs {
+		if v, ok := lookupSysattr(d, sysattr); ok && fnmatch(pattern, v) {
+			return false
+		}
+	}
+	for sysattr, pattern := range f.Sysattrs {
+		if v, ok := lookupSysattr(d, sysattr); !ok || !fnmatch(pattern, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupSysattr retrieves the value of a sys attribute of a device, and whether the device has it
+func lookupSysattr(d DeviceInfo, sysattr string) (string, bool) {
+	if l, ok := d.(interface {
+		lookupSysattr(string) (string, bool)
+	}); ok {
+		return l.lookupSysattr(sysattr)
+	}
+	_, ok := d.Sysattrs()[sysattr]
+	return d.SysattrValue(sysattr), ok
+}
+
+// Apply adds the matches of the filter to an enumerate.
+func (f *Filter) Apply(e *Enumerate) error {
+	for _, s := range f.Subsystems {
+		if err := e.AddMatchSubsystem(s); err != nil {
+			return err
+		}
+	}
+	for _, s := range f.NomatchSubsystems {
+		if err := e.AddNomatchSubsystem(s); err != nil {
+			return err
+		}
+	}
+	for _, s := range f.Sysnames {
+		if err := e.AddMatchSysname(s); err != nil {
+			return err
+		}
+	}
+	for _, k := range sortedMapKeys(f.Properties) {
+		if err := e.AddMatchProperty(k, f.Properties[k]); err != nil {
+			return err
+		}
+	}
+	for _, k := range sortedMapKeys(f.Sysattrs) {
+		if err := e.AddMatchSysattr(k, f.Sysattrs[k]); err != nil {
+			return err
+		}
+	}
+	for _, k := range sortedMapKeys(f.NomatchSysattrs) {
+		if err := e.AddNomatchSysattr(k, f.NomatchSysattrs[k]); err != nil {
+			return err
+		}
+	}
+	if f.Devtype != "" {
+		if len(f.Properties) == 0 {
+			if err := e.AddMatchProperty("DEVTYPE", escapePattern(f.Devtype)); err != nil {
+				return err
+			}
+		} else {
+			// Enumerate matches any of the properties, so the device type is matched on its own
+			e.addMatchDevtype(f.Devtype)
+		}
+	}
+	for _, t := range f.Tags {
+		if err := e.AddMatchTag(t); err != nil {
+			return err
+		}
+	}
+	for _, t := range f.CurrentTags {
+		if err := e.AddMatchCurrentTag(t); err != nil {
+			return err
+		}
+	}
+	if f.Parent != nil {
+		// Other devices, like those of an ExportDB, are looked up by their syspath
+		parent, ok := f.Parent.(*Device)
+		if !ok {
+			var err error
+			if parent, err = e.u.DeviceFromSyspath(f.Parent.Syspath()); err != nil {
+				return err
+			}
+		}
+		if err := e.AddMatchParent(parent); err != nil {
+			return err
+		}
+	}
+	if f.IsInitialized {
+		if err := e.AddMatchIsInitialized(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Install replaces the filters of a monitor with the filter.
+// The subsystems, device type and tags are added to the socket filter executed inside the kernel,
+// as long as the subsystems are not patterns, and the filter is evaluated for every device received.
+// The sys attributes are not evaluated for removed devices, as these no longer have any.
+// The filter must be installed before the monitor is switched to listening mode with the DeviceChan function,
+// otherwise FilterUpdate needs to be called.
+func (f *Filter) Install(m *Monitor) error {
+	// Removing fails if no socket filter was attached yet, but drops all filters anyway
+	m.FilterRemove()
+	if !anyPattern(f.Subsystems) {
+		for _, s := range f.Subsystems {
+			var err error
+			if f.Devtype == "" {
+				err = m.FilterAddMatchSubsystem(s)
+			} else {
+				err = m.FilterAddMatchSubsystemDevtype(s, f.Devtype)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// The socket filter matches any of the tags
+	for _, t := range f.Tags {
+		if err := m.FilterAddMatchTag(t); err != nil {
+			return err
+		}
+	}
+	for _, t := range f.CurrentTags {
+		if err := m.FilterAddMatchCurrentTag(t); err != nil {
+			return err
+		}
+	}
+	m.lock()
+	defer m.unlock()
+	m.filter = f
+	return nil
+}
+
+// FilterAddMatchCurrentTag adds a filter matching the device against a tag still applying to it, see Device.CurrentTags.
+// The devices having the tag are matched by the socket filter like for FilterAddMatchTag, and the current tags of these
+// devices are evaluated for every device received.
+// The filter must be installed before the monitor is switched to listening mode.
+func (m *Monitor) FilterAddMatchCurrentTag(tag string) (err error) {
+	if err = m.FilterAddMatchTag(tag); err != nil {
+		return
+	}
+	m.lock()
+	defer m.unlock()
+	m.currentTagFilter = append(m.currentTagFilter, tag)
+	return
+}
+
+// matchFilter reports whether a device received passes the current tags matched and the Filter installed on the monitor
+func (m *Monitor) matchFilter(d *Device) bool {
+	m.lock()
+	f, tags := m.filter, m.currentTagFilter
+	m.unlock()
+	for _, t := range tags {
+		if !d.HasCurrentTag(t) {
+			return false
+		}
+	}
+	return f == nil || f.match(d, d.Action() != "remove")
+}
+
+// anyMatch reports whether the name matches any of the patterns
+func anyMatch(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if fnmatch(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyPattern reports whether any of the strings is a pattern rather than a literal
+func anyPattern(s []string) bool {
+	for _, p := range s {
+		if strings.ContainsAny(p, "*?[\\") {
+			return true
+		}
+	}
+	return false
+}
+
+// escapePattern escapes the special characters of a pattern, so it matches the string literally
+func escapePattern(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte("*?[\\", s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// sortedMapKeys returns the keys of a map in sorted order
+func sortedMapKeys(m map[string]string) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,201 @@
+// +build linux
+
+package udev
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func ExampleFilter() {
+
+	// Create Udev and a Filter for block disks
+	u := Udev{}
+	f := Filter{
+		Subsystems: []string{"block"},
+		Devtype:    "disk",
+		Properties: map[string]string{"ID_BUS": "usb"},
+	}
+
+	// Enumerate the devices matching the filter
+	e := u.NewEnumerate()
+	f.Apply(e)
+	devices, _ := e.Devices()
+	for _, d := range devices {
+		fmt.Println(d.Syspath())
+	}
+
+	// Install the same filter on a monitor
+	m := u.NewMonitorFromNetlink("udev")
+	f.Install(m)
+}
+
+func TestFilterMatch(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		f     Filter
+		match bool
+	}{
+		{Filter{}, true},
+		{Filter{Subsystems: []string{"block", "mem"}}, true},
+		{Filter{Subsystems: []string{"m?m"}}, true},
+		{Filter{Subsystems: []string{"block"}}, false},
+		{Filter{NomatchSubsystems: []string{"me*"}}, false},
+		{Filter{Devtype: "disk"}, false},
+		{Filter{Sysnames: []string{"zero", "nu*"}}, true},
+		{Filter{Sysnames: []string{"zero"}}, false},
+		{Filter{Properties: map[string]string{"SUBSYSTEM": "mem", "DEVTYPE": "disk"}}, true},
+		{Filter{Properties: map[string]string{"DEV*": "/dev/null"}}, true},
+		{Filter{Properties: map[string]string{"SUBSYSTEM": "block"}}, false},
+		{Filter{Sysattrs: map[string]string{"dev": "1:3"}}, true},
+		{Filter{Sysattrs: map[string]string{"dev": "1:3", "missing": "*"}}, false},
+		{Filter{NomatchSysattrs: map[string]string{"dev": "1:*"}}, false},
+		{Filter{NomatchSysattrs: map[string]string{"missing": "*"}}, true},
+		{Filter{Tags: []string{"missing"}}, false},
+		{Filter{Parent: d}, true},
+	}
+	for i, test := range tests {
+		if test.f.Match(d) != test.match {
+			t.Errorf("Filter %d: %+v does not return %v", i, test.f, test.match)
+		}
+	}
+	// Removed devices are matched without their sys attributes
+	f := Filter{Sysattrs: map[string]string{"missing": "*"}}
+	if f.match(d, false) != true {
+		t.Error("Sysattrs matched")
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	u := Udev{}
+	for _, f := range []Filter{
+		{Subsystems: []string{"mem"}, Sysnames: []string{"[nz]*"}},
+		{Subsystems: []string{"block"}, Devtype: "disk"},
+		{Subsystems: []string{"block"}, Devtype: "partition"},
+		{Subsystems: []string{"block"}, Devtype: "disk", Properties: map[string]string{"DEVNAME": "*", "MISSING": "*"}},
+	} {
+		e := u.NewEnumerate()
+		if err := f.Apply(e); err != nil {
+			t.Fatal(err)
+		}
+		devices, err := e.Devices()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range devices {
+			if !f.Match(d) {
+				t.Errorf("Device %s does not match %+v", d.Syspath(), f)
+			}
+		}
+		// All devices of the subsystem matching the filter are enumerated
+		e = u.NewEnumerate()
+		e.AddMatchSubsystem(f.Subsystems[0])
+		all, err := e.Devices()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, d := range all {
+			if f.Match(d) {
+				n++
+			}
+		}
+		if n != len(devices) {
+			t.Errorf("Enumerated %d devices instead of %d for %+v", len(devices), n, f)
+		}
+	}
+}
+
+func TestFilterApplyParent(t *testing.T) {
+	u := Udev{}
+	// A parent which is no *Device is looked up by its syspath
+	f := Filter{Parent: NewMemDevice(nil, map[string]string{"DEVPATH": "/devices/virtual/mem/null"}, nil)}
+	e := u.NewEnumerate()
+	if err := f.Apply(e); err != nil {
+		t.Fatal(err)
+	}
+	syspaths, err := e.DeviceSyspaths()
+	if err != nil || len(syspaths) != 1 || syspaths[0] != "/sys/devices/virtual/mem/null" {
+		t.Error("Wrong devices", syspaths, err)
+	}
+	f = Filter{Parent: NewMemDevice(nil, map[string]string{"DEVPATH": "/devices/virtual/mem/missing"}, nil)}
+	if err := f.Apply(u.NewEnumerate()); err == nil {
+		t.Error("No error for missing parent")
+	}
+}
+
+func TestFilterApplyDevtype(t *testing.T) {
+	u := newTestSysfs(t).udev()
+	tests := []struct {
+		f        Filter
+		sysnames string
+	}{
+		{Filter{Devtype: "partition"}, "sda1"},
+		{Filter{Devtype: "disk", Properties: map[string]string{"ID_BUS": "ata"}}, "sda"},
+		{Filter{Devtype: "partition", Properties: map[string]string{"ID_NET_NAME_PATH": "*"}}, ""},
+		{Filter{Devtype: "d?sk"}, ""},
+	}
+	for i, test := range tests {
+		e := u.NewEnumerate()
+		if err := test.f.Apply(e); err != nil {
+			t.Fatal(err)
+		}
+		devices, err := e.Devices()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sysnames []string
+		for _, d := range devices {
+			sysnames = append(sysnames, d.Sysname())
+		}
+		if strings.Join(sysnames, " ") != test.sysnames {
+			t.Errorf("Filter %d enumerated %v", i, sysnames)
+		}
+	}
+}
+
+func TestFilterInstall(t *testing.T) {
+	u := Udev{}
+	m, err := u.MonitorFromNetlink("kernel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := Filter{Subsystems: []string{"block"}, Devtype: "disk", Tags: []string{"systemd"}}
+	if err := f.Install(m); err != nil {
+		t.Fatal(err)
+	}
+	if !m.hasFilter() || m.filter != &f {
+		t.Error("Filter not installed")
+	}
+	// Patterns can't be matched by the socket filter
+	f = Filter{Subsystems: []string{"bl*"}}
+	if err := f.Install(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.hasFilter() || m.filter != &f {
+		t.Error("Filter not replaced")
+	}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.matchFilter(d) {
+		t.Error("Device passes filter")
+	}
+	m.FilterRemove()
+	if !m.matchFilter(d) {
+		t.Error("Filter not removed")
+	}
+}
+
+func TestEscapePattern(t *testing.T) {
+	s := `a*b?c[d]\e`
+	if !fnmatch(escapePattern(s), s) || fnmatch(escapePattern(s), "axb?c[d]\\e") {
+		t.Error(escapePattern(s))
+	}
+}
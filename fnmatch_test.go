@@ -0,0 +1,33 @@
+// +build linux
+
+package udev
+
+import "testing"
+
+func TestFnmatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"block", "block", true},
+		{"block", "blocks", false},
+		{"*", "", true},
+		{"*", "/dev/disk/by-id", true},
+		{"/dev/*-id", "/dev/disk/by-id", true},
+		{"sd?", "sda", true},
+		{"sd?", "sd", false},
+		{"sd[a-c]1", "sdb1", true},
+		{"sd[!a-c]1", "sdb1", false},
+		{"sd[]a]", "sd]", true},
+		{"sd[a", "sd[a", true},
+		{"\\*", "*", true},
+		{"\\*", "a", false},
+		{"", "", true},
+		{"", "a", false},
+	}
+	for _, tt := range tests {
+		if fnmatch(tt.pattern, tt.name) != tt.match {
+			t.Errorf("fnmatch(%q, %q) != %v", tt.pattern, tt.name, tt.match)
+		}
+	}
+}
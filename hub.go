@@ -0,0 +1,160 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Hub owns an event source, a monitor or a replay, and fans the devices it receives out to many subscribers,
+// each with its own filter, channel capacity and drop policy.
+type Hub struct {
+	src EventSource
+
+	// Mutex guarding the subscriptions, held while a device is sent to them
+	mu      sync.Mutex
+	subs    []*Subscription
+	stopped bool
+	err     error
+
+	overruns atomic.Uint64
+}
+
+// Subscription receives the devices matching its filter from a Hub
+type Subscription struct {
+	// C is the channel on which the devices are sent, and is closed when unsubscribing or when the hub stops
+	C <-chan DeviceInfo
+
+	h      *Hub
+	q      *deviceQueue[DeviceInfo]
+	filter Filter
+}
+
+// NewHub returns a pointer to a new hub owning the event source, like a Monitor or a Replay.
+// Filters added to a monitor apply to all subscribers.
+func NewHub(src EventSource) *Hub {
+	return &Hub{src: src}
+}
+
+// Start switches the event source to listening mode and spawns a goroutine sending the devices received to the subscribers.
+// The function takes a context as argument, which when done will stop the goroutine and close the channels
+// of all subscriptions. If the goroutine stops because of an error, Err returns the error.
+func (h *Hub) Start(ctx context.Context) error {
+	devices, errs, err := h.src.DeviceInfoChan(ctx, ChanOptions{Errors: true})
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer h.stop(ctx)
+		for {
+			select {
+			case d, ok := <-devices:
+				if !ok {
+					return
+				}
+				h.dispatch(ctx, d)
+			case err, ok := <-errs:
+				if !ok {
+					return
+				}
+				if errors.Is(err, ErrOverrun) {
+					h.overruns.Add(1)
+					continue
+				}
+				h.mu.Lock()
+				h.err = err
+				h.mu.Unlock()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Subscribe adds a subscriber receiving the devices matching the filter on a channel with the capacity given.
+// The policy decides what happens to devices when the channel is full.
+// The sys attributes of the filter are not evaluated for removed devices, as these no longer have any.
+func (h *Hub) Subscribe(f Filter, capacity int, policy DropPolicy) *Subscription {
+	q := newDeviceQueue[DeviceInfo](capacity, policy)
+	s := &Subscription{
+		C:      q.ch,
+		h:      h,
+		q:      q,
+		filter: f,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		q.close()
+	} else {
+		h.subs = append(h.subs, s)
+	}
+	return s
+}
+
+// Err returns the error which stopped the hub, or nil if it is running or the context is done
+func (h *Hub) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// Overruns returns the number of times uevents were lost because the receive buffer of the monitor overflowed
+func (h *Hub) Overruns() uint64 {
+	return h.overruns.Load()
+}
+
+// stop closes the channels of all subscriptions, dropping the devices not sent yet if the context is done
+func (h *Hub) stop(ctx context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.subs {
+		if ctx.Err() != nil {
+			s.q.abort()
+		}
+		s.q.close()
+	}
+	h.subs = nil
+	h.stopped = true
+}
+
+// dispatch sends a device to all subscribers with a matching filter
+func (h *Hub) dispatch(ctx context.Context, d DeviceInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sysattrs := d.Action() != "remove"
+	for _, s := range h.subs {
+		if s.filter.match(d, sysattrs) {
+			s.q.send(ctx, d)
+		}
+	}
+}
+
+// Unsubscribe removes the subscriber from the hub and closes its channel
+func (s *Subscription) Unsubscribe() {
+	// Stop a blocked send before waiting for the hub
+	s.q.abort()
+	h := s.h
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, sub := range h.subs {
+		if sub == s {
+			h.subs = append(h.subs[:i], h.subs[i+1:]...)
+			s.q.close()
+			break
+		}
+	}
+}
+
+// Dropped returns the number of devices dropped because the channel of the subscriber was full
+func (s *Subscription) Dropped() uint64 {
+	return s.q.dropped.Load()
+}
+
+// Coalesced returns the number of devices replaced by a newer one with the Coalesce policy
+func (s *Subscription) Coalesced() uint64 {
+	return s.q.coalesced.Load()
+}
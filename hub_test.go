@@ -0,0 +1,127 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ExampleHub() {
+
+	// Create Udev, Monitor and Hub
+	u := Udev{}
+	h := NewHub(u.NewMonitorFromNetlink("udev"))
+
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Subscribe to block and network devices, dropping the oldest network devices if not received in time
+	block := h.Subscribe(Filter{Subsystems: []string{"block"}}, 16, Block)
+	net := h.Subscribe(Filter{Subsystems: []string{"net"}}, 16, DropOldest)
+	h.Start(ctx)
+
+	go func() {
+		for d := range net.C {
+			fmt.Println("Net:", d.Syspath(), d.Action())
+		}
+	}()
+	for d := range block.C {
+		fmt.Println("Block:", d.Syspath(), d.Action())
+	}
+}
+
+func TestHubDispatch(t *testing.T) {
+	u := Udev{}
+	m, err := u.MonitorFromNetlink("kernel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHub(m)
+	null, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, err := u.DeviceFromSubsystemSysname("mem", "zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	all := h.Subscribe(Filter{}, 1, DropNewest)
+	oldest := h.Subscribe(Filter{}, 1, DropOldest)
+	unbuffered := h.Subscribe(Filter{}, 0, DropOldest)
+	onlyZero := h.Subscribe(Filter{Sysnames: []string{"zero"}}, 2, Block)
+	h.dispatch(ctx, null)
+	h.dispatch(ctx, zero)
+	if d := <-all.C; d != null || all.Dropped() != 1 {
+		t.Error("DropNewest did not keep the oldest device")
+	}
+	if d := <-oldest.C; d != zero || oldest.Dropped() != 1 {
+		t.Error("DropOldest did not keep the newest device")
+	}
+	if unbuffered.Dropped() != 2 {
+		t.Error("Unbuffered subscription did not drop")
+	}
+	if d := <-onlyZero.C; d != zero || len(onlyZero.C) != 0 {
+		t.Error("Filter not evaluated")
+	}
+	// Unsubscribing closes the channel and stops a blocked send
+	block := h.Subscribe(Filter{}, 0, Block)
+	go func() {
+		<-time.After(100 * time.Millisecond)
+		block.Unsubscribe()
+	}()
+	h.dispatch(ctx, null)
+	if _, ok := <-block.C; ok {
+		t.Error("Channel not closed")
+	}
+	// Stopping closes all channels, and subscriptions after stopping are closed
+	h.stop(ctx)
+	for _, s := range []*Subscription{all, oldest, unbuffered, onlyZero, h.Subscribe(Filter{}, 1, Block)} {
+		for range s.C {
+		}
+	}
+}
+
+func TestHubStart(t *testing.T) {
+	u := Udev{}
+	m, err := u.MonitorFromNetlink("kernel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHub(m)
+	s := h.Subscribe(Filter{}, 16, DropOldest)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range s.C {
+	}
+	if h.Err() != nil {
+		t.Error(h.Err())
+	}
+}
+
+func TestHubReplay(t *testing.T) {
+	h := NewHub(NewReplay(strings.NewReader(`{"action":"add","seqnum":1,"syspath":"/sys/devices/virtual/block/loop0","properties":{"SUBSYSTEM":"block"}}
+{"action":"add","seqnum":2,"syspath":"/sys/devices/virtual/net/lo","properties":{"SUBSYSTEM":"net"}}
+{"action":"remove","seqnum":3,"syspath":"/sys/devices/virtual/block/loop0","properties":{"SUBSYSTEM":"block"}}
+`)))
+	s := h.Subscribe(Filter{Subsystems: []string{"block"}}, 16, Block)
+	if err := h.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The channels are closed after the last record
+	var seqnums []uint64
+	for d := range s.C {
+		seqnums = append(seqnums, d.Seqnum())
+	}
+	if fmt.Sprint(seqnums) != "[1 3]" || h.Err() != nil {
+		t.Error("Wrong devices replayed", seqnums, h.Err())
+	}
+}
@@ -0,0 +1,280 @@
+// +build linux
+
+package udev
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Event types of input devices, see linux/input-event-codes.h
+const (
+	EvSyn      = 0x00
+	EvKey      = 0x01
+	EvRel      = 0x02
+	EvAbs      = 0x03
+	EvMsc      = 0x04
+	EvSw       = 0x05
+	EvLed      = 0x11
+	EvSnd      = 0x12
+	EvRep      = 0x14
+	EvFf       = 0x15
+	EvPwr      = 0x16
+	EvFfStatus = 0x17
+)
+
+// Key and button codes of input devices, see linux/input-event-codes.h
+const (
+	KeyEsc           = 1
+	KeyEnter         = 28
+	KeyA             = 30
+	KeyZ             = 44
+	KeySpace         = 57
+	KeyVolumeDown    = 114
+	KeyVolumeUp      = 115
+	KeyPower         = 116
+	BtnMisc          = 0x100
+	BtnMouse         = 0x110
+	BtnLeft          = 0x110
+	BtnRight         = 0x111
+	BtnMiddle        = 0x112
+	BtnJoystick      = 0x120
+	BtnTrigger       = 0x120
+	BtnGamepad       = 0x130
+	BtnSouth         = 0x130
+	BtnDigi          = 0x140
+	BtnToolPen       = 0x140
+	BtnToolRubber    = 0x141
+	BtnToolFinger    = 0x145
+	BtnTouch         = 0x14a
+	BtnStylus        = 0x14b
+	BtnToolDoubletap = 0x14d
+)
+
+// Relative axes of input devices, see linux/input-event-codes.h
+const (
+	RelX      = 0x00
+	RelY      = 0x01
+	RelZ      = 0x02
+	RelHwheel = 0x06
+	RelDial   = 0x07
+	RelWheel  = 0x08
+)
+
+// Absolute axes of input devices, see linux/input-event-codes.h
+const (
+	AbsX             = 0x00
+	AbsY             = 0x01
+	AbsZ             = 0x02
+	AbsRx            = 0x03
+	AbsRy            = 0x04
+	AbsRz            = 0x05
+	AbsThrottle      = 0x06
+	AbsPressure      = 0x18
+	AbsMtSlot        = 0x2f
+	AbsMtPositionX   = 0x35
+	AbsMtPositionY   = 0x36
+	AbsMtTrackingID  = 0x39
+	AbsMtPressure    = 0x3a
+	AbsMtToolType    = 0x37
+	AbsMtTouchMajor  = 0x30
+	AbsMtTouchMinor  = 0x31
+	AbsMtOrientation = 0x34
+)
+
+// Switches of input devices, see linux/input-event-codes.h
+const (
+	SwLid              = 0x00
+	SwTabletMode       = 0x01
+	SwHeadphoneInsert  = 0x02
+	SwMicrophoneInsert = 0x04
+	SwDock             = 0x05
+)
+
+// Properties of input devices, see linux/input-event-codes.h
+const (
+	InputPropPointer       = 0x00
+	InputPropDirect        = 0x01
+	InputPropButtonpad     = 0x02
+	InputPropSemiMT        = 0x03
+	InputPropTopbuttonpad  = 0x04
+	InputPropPointingStick = 0x05
+	InputPropAccelerometer = 0x06
+)
+
+// Bitset is a set of event types or codes, decoded from a capability bitmap of an input device
+type Bitset []uint64
+
+// Has reports whether the code is in the set
+func (b Bitset) Has(code int) bool {
+	i := code / 64
+	return code >= 0 && i < len(b) && b[i]&(1<<(code%64)) != 0
+}
+
+// Codes returns the codes in the set in ascending order
+func (b Bitset) Codes() []int {
+	var r []int
+	for i, w := range b {
+		for w != 0 {
+			n := bits.TrailingZeros64(w)
+			r = append(r, i*64+n)
+			w &^= 1 << n
+		}
+	}
+	return r
+}
+
+// Len returns the number of codes in the set
+func (b Bitset) Len() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// parseBitmap decodes a bitmap as written by the kernel: hexadecimal words of the size of a long,
+// separated by spaces with the most significant word first and leading zero words left out
+func parseBitmap(s string, wordSize int) (Bitset, error) {
+	words := strings.Fields(s)
+	b := make(Bitset, (len(words)*wordSize+63)/64)
+	for i, word := range words {
+		w, err := strconv.ParseUint(word, 16, wordSize)
+		if err != nil {
+			return nil, err
+		}
+		// The last word holds the lowest bits
+		shift := (len(words) - 1 - i) * wordSize
+		b[shift/64] |= w << (shift % 64)
+	}
+	return b, nil
+}
+
+// InputDevice is a view of a device of the input subsystem, like input5 or its event device event5,
+// with the capability bitmaps of the kernel decoded into Bitsets and the classification of udev as booleans.
+type InputDevice struct {
+	*Device
+}
+
+// NewInputDevice returns a pointer to a new input device view of the device, and an *Error if it is no input device
+func NewInputDevice(d *Device) (*InputDevice, error) {
+	if d.Subsystem() != "input" {
+		return nil, newError("NewInputDevice", d.Syspath(), syscall.EINVAL)
+	}
+	return &InputDevice{Device: d}, nil
+}
+
+// inputDevice returns the device holding the capabilities: the device itself, or the input device of an event device
+func (i *InputDevice) inputDevice() *Device {
+	if _, ok := i.lookupSysattr("capabilities/ev"); ok {
+		return i.Device
+	}
+	if p := i.ParentWithSubsystemDevtype("input", ""); p != nil {
+		return p
+	}
+	return i.Device
+}
+
+// bitmap decodes a capability bitmap sys attribute
+func (i *InputDevice) bitmap(op, sysattr string) (Bitset, error) {
+	d := i.inputDevice()
+	v, err := sysattrString(d, op, sysattr)
+	if err != nil {
+		return nil, err
+	}
+	b, err := parseBitmap(v, bits.UintSize)
+	if err != nil {
+		return nil, newError(op, d.Syspath()+"/"+sysattr, err)
+	}
+	return b, nil
+}
+
+// Name returns the name of the input device, as reported by its driver
+func (i *InputDevice) Name() (string, error) {
+	return sysattrString(i.inputDevice(), "InputDevice.Name", "name")
+}
+
+// EventTypes returns the event types the input device supports, like EvKey and EvAbs
+func (i *InputDevice) EventTypes() (Bitset, error) {
+	return i.bitmap("InputDevice.EventTypes", "capabilities/ev")
+}
+
+// Keys returns the keys and buttons of the input device, like KeyA and BtnLeft
+func (i *InputDevice) Keys() (Bitset, error) {
+	return i.bitmap("InputDevice.Keys", "capabilities/key")
+}
+
+// RelativeAxes returns the relative axes of the input device, like RelX and RelWheel
+func (i *InputDevice) RelativeAxes() (Bitset, error) {
+	return i.bitmap("InputDevice.RelativeAxes", "capabilities/rel")
+}
+
+// AbsoluteAxes returns the absolute axes of the input device, like AbsX and AbsMtPositionX
+func (i *InputDevice) AbsoluteAxes() (Bitset, error) {
+	return i.bitmap("InputDevice.AbsoluteAxes", "capabilities/abs")
+}
+
+// Switches returns the switches of the input device, like SwLid
+func (i *InputDevice) Switches() (Bitset, error) {
+	return i.bitmap("InputDevice.Switches", "capabilities/sw")
+}
+
+// Misc returns the miscellaneous events of the input device
+func (i *InputDevice) Misc() (Bitset, error) {
+	return i.bitmap("InputDevice.Misc", "capabilities/msc")
+}
+
+// LEDs returns the LEDs of the input device
+func (i *InputDevice) LEDs() (Bitset, error) {
+	return i.bitmap("InputDevice.LEDs", "capabilities/led")
+}
+
+// Props returns the properties of the input device, like InputPropDirect for a touchscreen.
+// Unlike the capabilities, the kernel exposes them in the properties sys attribute of the input device
+// rather than in the capabilities directory, which has no prop attribute, and udev reads them there too.
+func (i *InputDevice) Props() (Bitset, error) {
+	return i.bitmap("InputDevice.Props", "properties")
+}
+
+// isInput reports whether udev classified the input device with the property given
+func (i *InputDevice) isInput(key string) bool {
+	return i.PropertyValue(key) == "1"
+}
+
+// IsInput reports whether udev classified the device as an input device, ID_INPUT
+func (i *InputDevice) IsInput() bool { return i.isInput("ID_INPUT") }
+
+// IsKeyboard reports whether udev classified the input device as a keyboard, ID_INPUT_KEYBOARD
+func (i *InputDevice) IsKeyboard() bool { return i.isInput("ID_INPUT_KEYBOARD") }
+
+// IsKey reports whether udev classified the input device as having keys, ID_INPUT_KEY
+func (i *InputDevice) IsKey() bool { return i.isInput("ID_INPUT_KEY") }
+
+// IsMouse reports whether udev classified the input device as a mouse, ID_INPUT_MOUSE
+func (i *InputDevice) IsMouse() bool { return i.isInput("ID_INPUT_MOUSE") }
+
+// IsTouchpad reports whether udev classified the input device as a touchpad, ID_INPUT_TOUCHPAD
+func (i *InputDevice) IsTouchpad() bool { return i.isInput("ID_INPUT_TOUCHPAD") }
+
+// IsTouchscreen reports whether udev classified the input device as a touchscreen, ID_INPUT_TOUCHSCREEN
+func (i *InputDevice) IsTouchscreen() bool { return i.isInput("ID_INPUT_TOUCHSCREEN") }
+
+// IsTablet reports whether udev classified the input device as a graphics tablet, ID_INPUT_TABLET
+func (i *InputDevice) IsTablet() bool { return i.isInput("ID_INPUT_TABLET") }
+
+// IsTabletPad reports whether udev classified the input device as the pad of a graphics tablet, ID_INPUT_TABLET_PAD
+func (i *InputDevice) IsTabletPad() bool { return i.isInput("ID_INPUT_TABLET_PAD") }
+
+// IsJoystick reports whether udev classified the input device as a joystick, ID_INPUT_JOYSTICK
+func (i *InputDevice) IsJoystick() bool { return i.isInput("ID_INPUT_JOYSTICK") }
+
+// IsAccelerometer reports whether udev classified the input device as an accelerometer, ID_INPUT_ACCELEROMETER
+func (i *InputDevice) IsAccelerometer() bool { return i.isInput("ID_INPUT_ACCELEROMETER") }
+
+// IsPointingStick reports whether udev classified the input device as a pointing stick, ID_INPUT_POINTINGSTICK
+func (i *InputDevice) IsPointingStick() bool { return i.isInput("ID_INPUT_POINTINGSTICK") }
+
+// IsSwitch reports whether udev classified the input device as having switches, ID_INPUT_SWITCH
+func (i *InputDevice) IsSwitch() bool { return i.isInput("ID_INPUT_SWITCH") }
@@ -0,0 +1,175 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/bits"
+	"testing"
+)
+
+func ExampleInputDevice() {
+
+	// Create Udev and Enumerate
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("input")
+	e.AddMatchProperty("ID_INPUT_TOUCHSCREEN", "1")
+
+	// Pick the multi-touch screen by its event device
+	devices, _ := e.Devices()
+	for _, d := range devices {
+		i, err := NewInputDevice(d)
+		if err != nil || d.Devnode() == "" {
+			continue
+		}
+		abs, _ := i.AbsoluteAxes()
+		props, _ := i.Props()
+		if abs.Has(AbsMtPositionX) && props.Has(InputPropDirect) {
+			name, _ := i.Name()
+			fmt.Println(d.Devnode(), name)
+		}
+	}
+}
+
+func ExampleBitset() {
+
+	// The event types of a touchscreen, as read from capabilities/ev
+	ev := Bitset{0xb}
+	fmt.Println(ev.Has(EvAbs), ev.Has(EvRel), ev.Codes())
+	// Output: true false [0 1 3]
+}
+
+func TestInputDevice(t *testing.T) {
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("input")
+	devices, err := e.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range devices {
+		i, err := NewInputDevice(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every input device has event types, and event devices those of their input device
+		if ev, err := i.EventTypes(); err == nil && !ev.Has(EvSyn) {
+			t.Error("Wrong event types", d.Syspath(), ev.Codes())
+		}
+	}
+	null, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Skip(err)
+	}
+	if _, err := NewInputDevice(null); err == nil {
+		t.Error("No error for a device which is no input device")
+	}
+}
+
+func TestParseBitmap(t *testing.T) {
+	tests := []struct {
+		s        string
+		wordSize int
+		codes    []int
+	}{
+		{"0", 64, nil},
+		{"b", 64, []int{EvSyn, EvKey, EvAbs}},
+		{"400 0 0 0 0 0", 64, []int{BtnTouch}},
+		{"400 0 0 0 0 0 0 0 0 0 0", 32, []int{BtnTouch}},
+		{"600000 3", 32, []int{AbsX, AbsY, AbsMtPositionX, AbsMtPositionY}},
+		{"8000000000000001", 64, []int{0, 63}},
+	}
+	for _, test := range tests {
+		b, err := parseBitmap(test.s, test.wordSize)
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+			continue
+		}
+		if fmt.Sprint(b.Codes()) != fmt.Sprint(test.codes) || b.Len() != len(test.codes) {
+			t.Errorf("%q decoded as %v", test.s, b.Codes())
+		}
+		for _, c := range test.codes {
+			if !b.Has(c) {
+				t.Errorf("%q hasn't %d", test.s, c)
+			}
+		}
+	}
+	if _, err := parseBitmap("1 x", 64); err == nil {
+		t.Error("No error for invalid word")
+	}
+	if _, err := parseBitmap("1ffffffff", 32); err == nil {
+		t.Error("No error for word larger than a long")
+	}
+	var b Bitset
+	if b.Has(0) || b.Has(-1) || b.Len() != 0 {
+		t.Error("Empty bitset not empty")
+	}
+}
+
+func TestFakeSysfsInputDevice(t *testing.T) {
+	if bits.UintSize != 64 {
+		t.Skip("capability bitmaps written for 64 bit longs")
+	}
+	f := newFakeSysfs(t)
+	input := "/devices/platform/i2c-0/0-0038/input/input3"
+	touch := map[string]string{"ID_INPUT": "1", "ID_INPUT_TOUCHSCREEN": "1"}
+	f.add(fakeDevice{
+		devpath:   input,
+		subsystem: "input",
+		sysattrs: map[string]string{
+			// The input properties are next to the capabilities directory, not in it
+			"name": "Touchscreen", "properties": "2", "capabilities/ev": "b", "capabilities/key": "400 0 0 0 0 0",
+			"capabilities/abs": "60000000000003", "capabilities/rel": "0", "capabilities/sw": "0",
+		},
+		initialized: true,
+		properties:  touch,
+	})
+	f.add(fakeDevice{
+		devpath:     input + "/event3",
+		subsystem:   "input",
+		devname:     "input/event3",
+		devnum:      MkDev(13, 67),
+		initialized: true,
+		properties:  touch,
+	})
+	u := f.udev()
+	event3, err := u.DeviceFromSubsystemSysname("input", "event3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := NewInputDevice(event3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !i.IsInput() || !i.IsTouchscreen() || i.IsTouchpad() || i.IsMouse() || i.IsKeyboard() {
+		t.Error("Wrong classification")
+	}
+	if n, err := i.Name(); err != nil || n != "Touchscreen" {
+		t.Error("Wrong name", n, err)
+	}
+	if ev, err := i.EventTypes(); err != nil || fmt.Sprint(ev.Codes()) != fmt.Sprint([]int{EvSyn, EvKey, EvAbs}) {
+		t.Error("Wrong event types", ev.Codes(), err)
+	}
+	if k, err := i.Keys(); err != nil || !k.Has(BtnTouch) || k.Len() != 1 {
+		t.Error("Wrong keys", k.Codes(), err)
+	}
+	if a, err := i.AbsoluteAxes(); err != nil || !a.Has(AbsX) || !a.Has(AbsMtPositionY) || a.Has(AbsPressure) {
+		t.Error("Wrong absolute axes", a.Codes(), err)
+	}
+	if r, err := i.RelativeAxes(); err != nil || r.Len() != 0 {
+		t.Error("Wrong relative axes", r.Codes(), err)
+	}
+	if p, err := i.Props(); err != nil || !p.Has(InputPropDirect) || p.Has(InputPropPointer) {
+		t.Error("Wrong properties", p.Codes(), err)
+	}
+	if _, err := i.LEDs(); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("No error for missing LEDs", err)
+	}
+	null, _ := newTestSysfs(t).udev().DeviceFromSubsystemSysname("mem", "null")
+	if _, err := NewInputDevice(null); err == nil {
+		t.Error("No error for a device which is no input device")
+	}
+}
@@ -1,4 +1,4 @@
-// +build linux,cgo
+// +build linux,cgo,!purego
 
 package udev
 
@@ -10,25 +10,24 @@ package udev
 	#include <linux/kdev_t.h>
 */
 import "C"
-import (
-	"context"
-	"errors"
-	"syscall"
-
-	"golang.org/x/sys/unix"
-)
+import "golang.org/x/sys/unix"
 
 // Monitor is an opaque object handling an event source
 type Monitor struct {
-	ptr *C.struct_udev_monitor
-	u   *Udev
+	ptr  *C.struct_udev_monitor
+	name string
+	u    *Udev
+
+	// Set when a filter was added, as libudev does not expose its filters
+	filtered bool
+	// Current tags matched, evaluated for every device received
+	currentTagFilter []string
+	// Installed Filter, evaluated for every device received
+	filter *Filter
+	// Counters of the queue of the device channel
+	queue *queueCounters
 }
 
-const (
-	maxEpollEvents = 32
-	epollTimeout   = 1000
-)
-
 // Lock the udev context
 func (m *Monitor) lock() {
 	m.u.m.Lock()
@@ -49,10 +48,7 @@ func monitorUnref(m *Monitor) {
 func (m *Monitor) SetReceiveBufferSize(size int) (err error) {
 	m.lock()
 	defer m.unlock()
-	if C.udev_monitor_set_receive_buffer_size(m.ptr, (C.int)(size)) != 0 {
-		err = errors.New("udev: udev_monitor_set_receive_buffer_size failed")
-	}
-	return
+	return errorFromReturn(C.udev_monitor_set_receive_buffer_size(m.ptr, (C.int)(size)), "udev_monitor_set_receive_buffer_size", "")
 }
 
 // FilterAddMatchSubsystem adds a filter matching the device against a subsystem.
@@ -63,8 +59,8 @@ func (m *Monitor) FilterAddMatchSubsystem(subsystem string) (err error) {
 	defer m.unlock()
 	s := C.CString(subsystem)
 	defer freeCharPtr(s)
-	if C.udev_monitor_filter_add_match_subsystem_devtype(m.ptr, s, nil) != 0 {
-		err = errors.New("udev: udev_monitor_filter_add_match_subsystem_devtype failed")
+	if err = errorFromReturn(C.udev_monitor_filter_add_match_subsystem_devtype(m.ptr, s, nil), "udev_monitor_filter_add_match_subsystem_devtype", subsystem); err == nil {
+		m.filtered = true
 	}
 	return
 }
@@ -78,8 +74,8 @@ func (m *Monitor) FilterAddMatchSubsystemDevtype(subsystem, devtype string) (err
 	s, d := C.CString(subsystem), C.CString(devtype)
 	defer freeCharPtr(s)
 	defer freeCharPtr(d)
-	if C.udev_monitor_filter_add_match_subsystem_devtype(m.ptr, s, d) != 0 {
-		err = errors.New("udev: udev_monitor_filter_add_match_subsystem_devtype failed")
+	if err = errorFromReturn(C.udev_monitor_filter_add_match_subsystem_devtype(m.ptr, s, d), "udev_monitor_filter_add_match_subsystem_devtype", subsystem); err == nil {
+		m.filtered = true
 	}
 	return
 }
@@ -92,8 +88,8 @@ func (m *Monitor) FilterAddMatchTag(tag string) (err error) {
 	defer m.unlock()
 	t := C.CString(tag)
 	defer freeCharPtr(t)
-	if C.udev_monitor_filter_add_match_tag(m.ptr, t) != 0 {
-		err = errors.New("udev: udev_monitor_filter_add_match_tag failed")
+	if err = errorFromReturn(C.udev_monitor_filter_add_match_tag(m.ptr, t), "udev_monitor_filter_add_match_tag", tag); err == nil {
+		m.filtered = true
 	}
 	return
 }
@@ -103,106 +99,58 @@ func (m *Monitor) FilterAddMatchTag(tag string) (err error) {
 func (m *Monitor) FilterUpdate() (err error) {
 	m.lock()
 	defer m.unlock()
-	if C.udev_monitor_filter_update(m.ptr) != 0 {
-		err = errors.New("udev: udev_monitor_filter_update failed")
-	}
-	return
+	return errorFromReturn(C.udev_monitor_filter_update(m.ptr), "udev_monitor_filter_update", "")
 }
 
 // FilterRemove removes all filter from the Monitor.
 func (m *Monitor) FilterRemove() (err error) {
 	m.lock()
 	defer m.unlock()
-	if C.udev_monitor_filter_remove(m.ptr) != 0 {
-		err = errors.New("udev: udev_monitor_filter_remove failed")
-	}
-	return
+	// libudev drops its filters even if detaching the socket filter fails
+	m.filtered = false
+	m.currentTagFilter = nil
+	m.filter = nil
+	return errorFromReturn(C.udev_monitor_filter_remove(m.ptr), "udev_monitor_filter_remove", "")
 }
 
-// receiveDevice is a helper function receiving a device while the Mutex is locked
-func (m *Monitor) receiveDevice() (d *Device) {
+// hasFilter reports whether a filter was added to the monitor
+func (m *Monitor) hasFilter() bool {
 	m.lock()
 	defer m.unlock()
-	return m.u.newDevice(C.udev_monitor_receive_device(m.ptr))
+	return m.filtered
 }
 
-// DeviceChan binds the udev_monitor socket to the event source and spawns a
-// goroutine. The goroutine efficiently waits on the monitor socket using epoll.
-// Data is received from the udev monitor socket and a new Device is created
-// with the data received. Pointers to the device are sent on the returned
-// channel. The function takes a context as argument, which when done will stop
-// the goroutine and close the device channel. Only socket connections with
-// uid=0 are accepted.
-func (m *Monitor) DeviceChan(ctx context.Context) (<-chan *Device, error) {
-
-	var event unix.EpollEvent
-	var events [maxEpollEvents]unix.EpollEvent
+// receiveDevice is a helper function receiving a device while the Mutex is locked.
+// It returns unix.EAGAIN if no device is available and unix.ENOBUFS if uevents were lost.
+func (m *Monitor) receiveDevice() (*Device, error) {
+	m.lock()
+	defer m.unlock()
+	ptr, e := C.udev_monitor_receive_device(m.ptr)
+	if ptr == nil {
+		// Messages which were not accepted leave errno unset or set to some other error
+		if e != unix.ENOBUFS {
+			e = unix.EAGAIN
+		}
+		return nil, e
+	}
+	return m.u.newDevice(ptr), nil
+}
 
-	// Lock the context
+// enableReceiving binds the udev_monitor socket to the event source and
+// returns its file descriptor, set to non-blocking mode.
+func (m *Monitor) enableReceiving() (int, error) {
 	m.lock()
 	defer m.unlock()
 
 	// Enable receiving
-	if C.udev_monitor_enable_receiving(m.ptr) != 0 {
-		return nil, errors.New("udev: udev_monitor_enable_receiving failed")
+	if r := C.udev_monitor_enable_receiving(m.ptr); r < 0 {
+		return -1, errorFromReturn(r, "udev_monitor_enable_receiving", "")
 	}
 
 	// Set the fd to non-blocking
-	fd := C.udev_monitor_get_fd(m.ptr)
-	if e := unix.SetNonblock(int(fd), true); e != nil {
-		return nil, errors.New("udev: unix.SetNonblock failed")
-	}
-
-	// Create an epoll fd
-	epfd, e := unix.EpollCreate1(0)
-	if e != nil {
-		return nil, errors.New("udev: unix.EpollCreate1 failed")
+	fd := int(C.udev_monitor_get_fd(m.ptr))
+	if e := unix.SetNonblock(fd, true); e != nil {
+		return -1, &Error{Op: "unix.SetNonblock", Err: e}
 	}
-
-	// Add the fd to the epoll fd
-	event.Events = unix.EPOLLIN | unix.EPOLLET
-	event.Fd = int32(fd)
-	if e = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, int(fd), &event); e != nil {
-		return nil, errors.New("udev: unix.EpollCtl failed")
-	}
-
-	// Create the channel
-	ch := make(chan *Device)
-
-	// Create goroutine to epoll the fd
-	go func(fd int32) {
-		// Close the epoll fd when goroutine exits
-		defer unix.Close(epfd)
-		// Close the channel when goroutine exits
-		defer close(ch)
-		// Loop forever
-		for {
-			// Poll the file descriptor
-			nevents, e := unix.EpollWait(epfd, events[:], epollTimeout)
-			// Ignore the EINTR error case since cancelation is performed with the
-			// context's Done() channel
-			errno, isErrno := e.(syscall.Errno)
-			if (e != nil && !isErrno) || (isErrno && errno != syscall.EINTR) {
-				return
-			}
-			// Check for done signal
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-			// Process events
-			for ev := 0; ev < nevents; ev++ {
-				if events[ev].Fd == fd {
-					if (events[ev].Events & unix.EPOLLIN) != 0 {
-						for d := m.receiveDevice(); d != nil; d = m.receiveDevice() {
-							ch <- d
-						}
-					}
-				}
-			}
-		}
-	}(int32(fd))
-
-	return ch, nil
+	return fd, nil
 }
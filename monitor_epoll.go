@@ -0,0 +1,250 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	maxEpollEvents = 32
+	epollTimeout   = 1000
+)
+
+// ChanOptions configures the channels returned by DeviceChanWithOptions
+type ChanOptions struct {
+	// Capacity is the capacity of the device channel
+	Capacity int
+	// Policy decides what happens to devices when the device channel is full
+	Policy DropPolicy
+	// Errors enables the error channel, see DeviceErrChan
+	Errors bool
+}
+
+// EventSource is the channel API shared by Monitor and Replay, sending the devices as DeviceInfo,
+// so that code consuming the devices of a monitor can also consume recorded devices.
+type EventSource interface {
+	// DeviceInfoChan is like Monitor.DeviceChanWithOptions, with a channel of DeviceInfo
+	DeviceInfoChan(ctx context.Context, opts ChanOptions) (<-chan DeviceInfo, <-chan error, error)
+}
+
+var (
+	_ EventSource = (*Monitor)(nil)
+	_ EventSource = (*Replay)(nil)
+)
+
+// DeviceChan binds the udev_monitor socket to the event source and spawns a
+// goroutine. The goroutine efficiently waits on the monitor socket using epoll.
+// Data is received from the udev monitor socket and a new Device is created
+// with the data received. Pointers to the device are sent on the returned
+// channel. The function takes a context as argument, which when done will stop
+// the goroutine and close the device channel. Only socket connections with
+// uid=0 are accepted.
+// Errors are not reported, use DeviceErrChan to learn about lost events and
+// why the channel was closed.
+func (m *Monitor) DeviceChan(ctx context.Context) (<-chan *Device, error) {
+	ch, _, e := m.DeviceChanWithOptions(ctx, ChanOptions{})
+	return ch, e
+}
+
+// DeviceErrChan is like DeviceChan, but also returns an error channel.
+// ErrOverrun is sent on the error channel whenever uevents were lost because the
+// receive buffer of the monitor socket overflowed, after which the devices
+// should be re-enumerated. If the goroutine stops because of an error, that
+// error is sent before both channels are closed. If it stops because the context
+// is done, the channels are closed without sending an error.
+// As long as no filter is installed, the sequence numbers of the events are
+// tracked and a *SeqnumGapError is sent for every range of lost events. Gaps in
+// udev events are only reported after a reorder window of 30 seconds, as udevd
+// may broadcast events out of order.
+// The error channel must be drained along with the device channel.
+func (m *Monitor) DeviceErrChan(ctx context.Context) (<-chan *Device, <-chan error, error) {
+	return m.DeviceChanWithOptions(ctx, ChanOptions{Errors: true})
+}
+
+// DeviceChanWithOptions is like DeviceChan, but with a device channel of the capacity
+// and drop policy given by the options. The error channel is nil unless enabled by the options.
+// With a policy other than Block, a slow receiver no longer delays receiving from the socket,
+// and Dropped and Coalesced count the devices dropped and replaced because the channel was full.
+func (m *Monitor) DeviceChanWithOptions(ctx context.Context, opts ChanOptions) (<-chan *Device, <-chan error, error) {
+	return monitorChan(m, ctx, opts, func(d *Device) *Device { return d })
+}
+
+// DeviceInfoChan is like DeviceChanWithOptions, but sends the devices as DeviceInfo, see EventSource
+func (m *Monitor) DeviceInfoChan(ctx context.Context, opts ChanOptions) (<-chan DeviceInfo, <-chan error, error) {
+	return monitorChan(m, ctx, opts, func(d *Device) DeviceInfo { return d })
+}
+
+// monitorChan implements the DeviceChan functions of the monitor for channels of any device type,
+// sending the devices received as converted by the function given
+func monitorChan[D DeviceInfo](m *Monitor, ctx context.Context, opts ChanOptions, convert func(*Device) D) (<-chan D, <-chan error, error) {
+
+	var event unix.EpollEvent
+	var events [maxEpollEvents]unix.EpollEvent
+
+	// Enable receiving on a non-blocking fd
+	fd, e := m.enableReceiving()
+	if e != nil {
+		return nil, nil, e
+	}
+
+	// Create an epoll fd
+	epfd, e := unix.EpollCreate1(0)
+	if e != nil {
+		return nil, nil, &Error{Op: "unix.EpollCreate1", Err: e}
+	}
+
+	// Add the fd to the epoll fd
+	event.Events = unix.EPOLLIN | unix.EPOLLET
+	event.Fd = int32(fd)
+	if e = unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, fd, &event); e != nil {
+		unix.Close(epfd)
+		return nil, nil, &Error{Op: "unix.EpollCtl", Err: e}
+	}
+
+	// Create the channels, and track sequence numbers for gap detection along with the error channel
+	q := newDeviceQueue[D](opts.Capacity, opts.Policy)
+	var errs chan error
+	var seqnums *seqnumTracker
+	if opts.Errors {
+		errs = make(chan error)
+		seqnums = newSeqnumTracker(m.name)
+	}
+
+	// report sends an error if there is an error channel, and returns false if the context is done
+	report := func(err error) bool {
+		if errs == nil {
+			return true
+		}
+		select {
+		case errs <- err:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// track tracks the sequence number of a device if there is no filter, and reports the gaps found
+	track := func(d *Device) bool {
+		if seqnums == nil {
+			return true
+		}
+		var gaps []*SeqnumGapError
+		if m.hasFilter() {
+			// Filtered events leave gaps in the sequence numbers
+			seqnums.reset()
+		} else if d != nil {
+			gaps = seqnums.add(d.Seqnum(), time.Now())
+		} else {
+			gaps = seqnums.expire(time.Now())
+		}
+		for _, g := range gaps {
+			if !report(g) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Create goroutine to epoll the fd
+	go func(fd int32) {
+		// Close the epoll fd when goroutine exits
+		defer unix.Close(epfd)
+		// Close the channels when goroutine exits, dropping pending devices if the context is done
+		defer func() {
+			if ctx.Err() != nil {
+				q.abort()
+			}
+			q.close()
+			if errs != nil {
+				close(errs)
+			}
+		}()
+		// Loop forever
+		for {
+			// Poll the file descriptor
+			nevents, e := unix.EpollWait(epfd, events[:], epollTimeout)
+			// Ignore the EINTR error case since cancelation is performed with the
+			// context's Done() channel
+			if e != nil && e != unix.EINTR {
+				report(&Error{Op: "unix.EpollWait", Err: e})
+				return
+			}
+			// Check for done signal
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			// Report gaps which did not fill within the reorder window
+			if !track(nil) {
+				return
+			}
+			// Process events
+			for ev := 0; ev < nevents; ev++ {
+				if events[ev].Fd == fd {
+					if (events[ev].Events & unix.EPOLLIN) != 0 {
+						// Read until the socket is drained, as the fd is edge triggered
+						for {
+							d, e := m.receiveDevice()
+							if e == unix.ENOBUFS {
+								if !report(ErrOverrun) {
+									return
+								}
+								continue
+							}
+							if e != nil {
+								break
+							}
+							if !track(d) {
+								return
+							}
+							// Evaluate the installed Filter, sequence numbers are tracked regardless
+							if m.matchFilter(d) && !q.send(ctx, convert(d)) {
+								return
+							}
+						}
+					}
+				}
+			}
+		}
+	}(int32(fd))
+
+	m.lock()
+	m.queue = &q.queueCounters
+	m.unlock()
+	return q.ch, errs, nil
+}
+
+// Dropped returns the number of devices dropped because the device channel was full,
+// counted since the last call to one of the DeviceChan functions
+func (m *Monitor) Dropped() uint64 {
+	m.lock()
+	defer m.unlock()
+	if m.queue == nil {
+		return 0
+	}
+	return m.queue.dropped.Load()
+}
+
+// Coalesced returns the number of devices replaced by a newer one with the Coalesce policy,
+// counted since the last call to one of the DeviceChan functions
+func (m *Monitor) Coalesced() uint64 {
+	m.lock()
+	defer m.unlock()
+	if m.queue == nil {
+		return 0
+	}
+	return m.queue.coalesced.Load()
+}
+
+// close releases the monitor right away rather than when it is garbage collected.
+// The monitor must not be used afterwards.
+func (m *Monitor) close() {
+	runtime.SetFinalizer(m, nil)
+	monitorUnref(m)
+}
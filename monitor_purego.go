@@ -0,0 +1,202 @@
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Monitor is an opaque object handling an event source
+type Monitor struct {
+	fd    int
+	group uint32
+	name  string
+	bound bool
+	u     *Udev
+
+	// Subsystem and devtype pairs, an empty devtype matches any devtype
+	subsystemFilter [][2]string
+	tagFilter       []string
+	// Current tags matched, evaluated for every device received
+	currentTagFilter []string
+	// Installed Filter, evaluated for every device received
+	filter *Filter
+	// Counters of the queue of the device channel
+	queue *queueCounters
+}
+
+// Lock the udev context
+func (m *Monitor) lock() {
+	m.u.m.Lock()
+}
+
+// Unlock the udev context
+func (m *Monitor) unlock() {
+	m.u.m.Unlock()
+}
+
+// Close the monitor socket
+func monitorUnref(m *Monitor) {
+	unix.Close(m.fd)
+}
+
+// SetReceiveBufferSize sets the size of the kernel socket buffer.
+// This call needs the appropriate privileges to succeed.
+func (m *Monitor) SetReceiveBufferSize(size int) (err error) {
+	m.lock()
+	defer m.unlock()
+	if e := unix.SetsockoptInt(m.fd, unix.SOL_SOCKET, unix.SO_RCVBUFFORCE, size); e != nil {
+		err = newError("udev_monitor_set_receive_buffer_size", "", e)
+	}
+	return
+}
+
+// FilterAddMatchSubsystem adds a filter matching the device against a subsystem.
+// This filter is efficiently executed inside the kernel, and libudev subscribers will usually not be woken up for devices which do not match.
+// The filter must be installed before the monitor is switched to listening mode with the DeviceChan function.
+func (m *Monitor) FilterAddMatchSubsystem(subsystem string) (err error) {
+	m.lock()
+	defer m.unlock()
+	m.subsystemFilter = append(m.subsystemFilter, [2]string{subsystem, ""})
+	return
+}
+
+// FilterAddMatchSubsystemDevtype adds a filter matching the device against a subsystem and device type.
+// This filter is efficiently executed inside the kernel, and libudev subscribers will usually not be woken up for devices which do not match.
+// The filter must be installed before the monitor is switched to listening mode with the DeviceChan function.
+func (m *Monitor) FilterAddMatchSubsystemDevtype(subsystem, devtype string) (err error) {
+	m.lock()
+	defer m.unlock()
+	m.subsystemFilter = append(m.subsystemFilter, [2]string{subsystem, devtype})
+	return
+}
+
+// FilterAddMatchTag adds a filter matching the device against a tag.
+// This filter is efficiently executed inside the kernel, and libudev subscribers will usually not be woken up for devices which do not match.
+// The filter must be installed before the monitor is switched to listening mode.
+func (m *Monitor) FilterAddMatchTag(tag string) (err error) {
+	m.lock()
+	defer m.unlock()
+	m.tagFilter = append(m.tagFilter, tag)
+	return
+}
+
+// hasFilter reports whether a filter was added to the monitor
+func (m *Monitor) hasFilter() bool {
+	m.lock()
+	defer m.unlock()
+	return len(m.subsystemFilter) > 0 || len(m.tagFilter) > 0
+}
+
+// filterUpdate installs the socket filter while the Mutex is locked
+func (m *Monitor) filterUpdate() error {
+	if len(m.subsystemFilter) == 0 && len(m.tagFilter) == 0 {
+		return nil
+	}
+	ins := m.socketFilter()
+	if len(ins) > maxFilterInstructions {
+		return newError("udev_monitor_filter_update", "", syscall.E2BIG)
+	}
+	prog := unix.SockFprog{Len: uint16(len(ins)), Filter: &ins[0]}
+	if e := unix.SetsockoptSockFprog(m.fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &prog); e != nil {
+		return newError("udev_monitor_filter_update", "", e)
+	}
+	return nil
+}
+
+// FilterUpdate updates the installed socket filter.
+// This is only needed, if the filter was removed or changed.
+func (m *Monitor) FilterUpdate() (err error) {
+	m.lock()
+	defer m.unlock()
+	return m.filterUpdate()
+}
+
+// FilterRemove removes all filter from the Monitor.
+func (m *Monitor) FilterRemove() (err error) {
+	m.lock()
+	defer m.unlock()
+	m.subsystemFilter = nil
+	m.tagFilter = nil
+	m.currentTagFilter = nil
+	m.filter = nil
+	if e := unix.SetsockoptInt(m.fd, unix.SOL_SOCKET, unix.SO_DETACH_FILTER, 0); e != nil && e != unix.ENOENT {
+		err = newError("udev_monitor_filter_remove", "", e)
+	}
+	return
+}
+
+// receive receives one message from the socket, and returns nil if the message was not accepted
+func (m *Monitor) receive() (*Device, error) {
+	var buf [8192]byte
+	oob := make([]byte, unix.CmsgSpace(unix.SizeofUcred))
+	n, oobn, _, from, err := unix.Recvmsg(m.fd, buf[:], oob, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Only accept multicast messages from the kernel for the kernel group, and from userspace for the udev group
+	sa, ok := from.(*unix.SockaddrNetlink)
+	if !ok || sa.Groups == netlinkGroupNone {
+		return nil, nil
+	}
+	if (sa.Groups == netlinkGroupKernel) != (sa.Pid == 0) {
+		return nil, nil
+	}
+	// Only accept messages sent by root
+	cmsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(cmsgs) == 0 {
+		return nil, nil
+	}
+	cred, err := unix.ParseUnixCredentials(&cmsgs[0])
+	if err != nil || cred.Uid != 0 {
+		return nil, nil
+	}
+	d, err := m.u.newDeviceFromUevent(buf[:n])
+	if err != nil || !m.passesFilter(d) {
+		return nil, nil
+	}
+	return d, nil
+}
+
+// receiveDevice is a helper function receiving a device while the Mutex is locked.
+// It returns unix.EAGAIN if no device is available and unix.ENOBUFS if uevents were lost.
+func (m *Monitor) receiveDevice() (*Device, error) {
+	m.lock()
+	defer m.unlock()
+	for {
+		d, err := m.receive()
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || d != nil {
+			return d, err
+		}
+	}
+}
+
+// enableReceiving binds the socket to the event source and
+// returns its file descriptor, set to non-blocking mode.
+func (m *Monitor) enableReceiving() (int, error) {
+	m.lock()
+	defer m.unlock()
+
+	// Install the filter and bind the socket
+	if e := m.filterUpdate(); e != nil {
+		return -1, newError("udev_monitor_enable_receiving", "", errors.Unwrap(e))
+	}
+	if !m.bound {
+		if e := unix.Bind(m.fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: m.group}); e != nil {
+			return -1, newError("udev_monitor_enable_receiving", "", e)
+		}
+		m.bound = true
+	}
+	// Enable receiving of the sender credentials
+	if e := unix.SetsockoptInt(m.fd, unix.SOL_SOCKET, unix.SO_PASSCRED, 1); e != nil {
+		return -1, newError("udev_monitor_enable_receiving", "", e)
+	}
+	return m.fd, nil
+}
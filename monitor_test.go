@@ -4,6 +4,7 @@ package udev
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
 	"sync"
@@ -97,6 +98,93 @@ func TestMonitorDeviceChan(t *testing.T) {
 
 }
 
+func ExampleMonitor_DeviceErrChan() {
+
+	// Create Udev and Monitor
+	u := Udev{}
+	m := u.NewMonitorFromNetlink("udev")
+
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Start monitor goroutine and get receive and error channels
+	ch, errs, _ := m.DeviceErrChan(ctx)
+	for ch != nil || errs != nil {
+		select {
+		case d, ok := <-ch:
+			if !ok {
+				ch = nil
+				continue
+			}
+			fmt.Println("Event:", d.Syspath(), d.Action())
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if errors.Is(err, ErrOverrun) {
+				fmt.Println("Events were lost, re-enumerate devices")
+			} else {
+				fmt.Println("Monitor stopped:", err)
+			}
+		}
+	}
+}
+
+func TestMonitorDeviceErrChan(t *testing.T) {
+	u := Udev{}
+	m := u.NewMonitorFromNetlink("kernel")
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, errs, e := m.DeviceErrChan(ctx)
+	if e != nil {
+		t.Fatal(e)
+	}
+	go func() {
+		<-time.After(time.Second)
+		cancel()
+	}()
+	// Drain both channels, which must be closed without an error once the context is done
+	for ch != nil || errs != nil {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				ch = nil
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+			} else if !errors.Is(err, ErrOverrun) {
+				t.Error(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Channels not closed")
+		}
+	}
+}
+
+func TestMonitorDeviceChanWithOptions(t *testing.T) {
+	u := Udev{}
+	m := u.NewMonitorFromNetlink("kernel")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ch, errs, e := m.DeviceChanWithOptions(ctx, ChanOptions{Capacity: 64, Policy: Coalesce})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if errs != nil {
+		t.Error("Error channel not disabled")
+	}
+	if cap(ch) != 0 {
+		t.Error("Coalescing channel is buffered")
+	}
+	for range ch {
+	}
+	if m.Dropped() != 0 {
+		t.Error("Devices dropped")
+	}
+}
+
 func TestMonitorGC(t *testing.T) {
 	runtime.GC()
 }
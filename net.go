@@ -0,0 +1,121 @@
+// +build linux
+
+package udev
+
+import (
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// NetDevice is a view of a network interface, a device of the net subsystem,
+// with its address and link state from the kernel and the interface names proposed by udev.
+type NetDevice struct {
+	*Device
+}
+
+// NewNetDevice returns a pointer to a new network interface view of the device, and an *Error if it is no network interface
+func NewNetDevice(d *Device) (*NetDevice, error) {
+	if d.Subsystem() != "net" {
+		return nil, newError("NewNetDevice", d.Syspath(), syscall.EINVAL)
+	}
+	return &NetDevice{Device: d}, nil
+}
+
+// Interface returns the name of the network interface
+func (n *NetDevice) Interface() string {
+	if v := n.PropertyValue("INTERFACE"); v != "" {
+		return v
+	}
+	return n.Sysname()
+}
+
+// Ifindex returns the index of the network interface
+func (n *NetDevice) Ifindex() (int, error) {
+	return sysattrInt(n.Device, "NetDevice.Ifindex", "ifindex")
+}
+
+// Address returns the hardware address of the network interface
+func (n *NetDevice) Address() (net.HardwareAddr, error) {
+	v, err := sysattrString(n.Device, "NetDevice.Address", "address")
+	if err != nil {
+		return nil, err
+	}
+	a, err := net.ParseMAC(v)
+	if err != nil {
+		return nil, newError("NetDevice.Address", n.Syspath()+"/address", err)
+	}
+	return a, nil
+}
+
+// OperState returns the operational state of the network interface as in RFC 2863, like up, down or dormant
+func (n *NetDevice) OperState() (string, error) {
+	return sysattrString(n.Device, "NetDevice.OperState", "operstate")
+}
+
+// MTU returns the maximum transmission unit of the network interface in bytes
+func (n *NetDevice) MTU() (int, error) {
+	return sysattrInt(n.Device, "NetDevice.MTU", "mtu")
+}
+
+// Carrier reports whether the network interface has a carrier, which can only be read while it is up
+func (n *NetDevice) Carrier() (bool, error) {
+	return sysattrBool(n.Device, "NetDevice.Carrier", "carrier")
+}
+
+// Speed returns the link speed of the network interface in Mbit/s, which is -1 if the speed is unknown
+func (n *NetDevice) Speed() (int, error) {
+	v, err := sysattrString(n.Device, "NetDevice.Speed", "speed")
+	switch {
+	case err != nil:
+		// Reading the speed fails with EINVAL while the link is down, or if the driver has no speed
+		if _, ok := n.Sysattrs()["speed"]; ok {
+			return -1, nil
+		}
+		return 0, err
+	case v == "4294967295":
+		// Older kernels print the unknown speed as an unsigned number
+		return -1, nil
+	}
+	s, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, newError("NetDevice.Speed", n.Syspath()+"/speed", err)
+	}
+	return s, nil
+}
+
+// NamePath returns the predictable name of the network interface derived from its bus path, like enp0s31f6
+func (n *NetDevice) NamePath() string {
+	return n.PropertyValue("ID_NET_NAME_PATH")
+}
+
+// NameMAC returns the predictable name of the network interface derived from its hardware address, like enx525400123456
+func (n *NetDevice) NameMAC() string {
+	return n.PropertyValue("ID_NET_NAME_MAC")
+}
+
+// NameSlot returns the predictable name of the network interface derived from its hotplug slot, like ens1
+func (n *NetDevice) NameSlot() string {
+	return n.PropertyValue("ID_NET_NAME_SLOT")
+}
+
+// NameOnboard returns the predictable name of the network interface derived from the firmware index, like eno1
+func (n *NetDevice) NameOnboard() string {
+	return n.PropertyValue("ID_NET_NAME_ONBOARD")
+}
+
+// BusDevice returns the device the network interface belongs to, like a PCI or USB device, or nil for a virtual interface
+func (n *NetDevice) BusDevice() *Device {
+	return n.Parent()
+}
+
+// BusDriver returns the driver of the network interface, which is bound to its bus device rather than the interface
+func (n *NetDevice) BusDriver() string {
+	if v := n.PropertyValue("ID_NET_DRIVER"); v != "" {
+		return v
+	}
+	if p := n.BusDevice(); p != nil {
+		return p.Driver()
+	}
+	return ""
+}
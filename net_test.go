@@ -0,0 +1,114 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+func ExampleNetDevice() {
+
+	// Create Udev and Enumerate
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("net")
+
+	// List the network interfaces with their state
+	devices, _ := e.Devices()
+	for _, d := range devices {
+		n, _ := NewNetDevice(d)
+		address, _ := n.Address()
+		state, _ := n.OperState()
+		fmt.Println(n.Interface(), address, state, n.NamePath(), n.BusDriver())
+	}
+}
+
+func TestNetDevice(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("net", "lo")
+	if err != nil {
+		t.Skip(err)
+	}
+	lo, err := NewNetDevice(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo.Interface() != "lo" {
+		t.Error("Wrong interface", lo.Interface())
+	}
+	if n, err := lo.Ifindex(); err != nil || n < 1 {
+		t.Error("Wrong ifindex", n, err)
+	}
+	// The loopback interface has no speed, reading it fails with EINVAL
+	if n, err := lo.Speed(); err != nil || n != -1 {
+		t.Error("Wrong speed", n, err)
+	}
+	if _, err := lo.Carrier(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Error(err)
+	}
+}
+
+func TestFakeSysfsNetDevice(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	d, _ := u.DeviceFromDeviceID("n2")
+	eth0, err := NewNetDevice(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eth0.Interface() != "eth0" || eth0.NamePath() != "enp0s31f2" || eth0.NameMAC() != "" || eth0.BusDriver() != "ahci" {
+		t.Error("Wrong interface", eth0.Interface(), eth0.NamePath(), eth0.BusDriver())
+	}
+	if p := eth0.BusDevice(); p == nil || p.Subsystem() != "pci" {
+		t.Error("Wrong bus device")
+	}
+	if n, err := eth0.Ifindex(); err != nil || n != 2 {
+		t.Error("Wrong ifindex", n, err)
+	}
+	if a, err := eth0.Address(); err != nil || a.String() != "52:54:00:12:34:56" {
+		t.Error("Wrong address", a, err)
+	}
+	if s, err := eth0.OperState(); err != nil || s != "up" {
+		t.Error("Wrong operstate", s, err)
+	}
+	if n, err := eth0.MTU(); err != nil || n != 1500 {
+		t.Error("Wrong MTU", n, err)
+	}
+	if c, err := eth0.Carrier(); err != nil || !c {
+		t.Error("Wrong carrier", c, err)
+	}
+	if n, err := eth0.Speed(); err != nil || n != -1 {
+		t.Error("Wrong speed", n, err)
+	}
+	// Errors are reported per field, and older kernels report the unknown speed as an unsigned number
+	f.write("sys/devices/pci0000:00/0000:00:1f.2/net/eth0/mtu", "x\n")
+	f.write("sys/devices/pci0000:00/0000:00:1f.2/net/eth0/speed", "4294967295\n")
+	d, _ = u.DeviceFromDeviceID("n2")
+	eth0, _ = NewNetDevice(d)
+	if _, err := eth0.MTU(); err == nil || !strings.Contains(err.Error(), "mtu") {
+		t.Error("No error for invalid MTU", err)
+	}
+	if _, err := eth0.OperState(); err != nil {
+		t.Error(err)
+	}
+	if n, err := eth0.Speed(); err != nil || n != -1 {
+		t.Error("Wrong unknown speed", n, err)
+	}
+	// The speed fails to read while the link is down, like the link listed as sys attribute which is not read
+	os.Remove(f.path("sys/devices/pci0000:00/0000:00:1f.2/net/eth0/speed"))
+	f.symlink("sys/devices/pci0000:00/0000:00:1f.2/net/eth0/speed", "sys/devices/pci0000:00/0000:00:1f.2/net/eth0/missing")
+	d, _ = u.DeviceFromDeviceID("n2")
+	eth0, _ = NewNetDevice(d)
+	if n, err := eth0.Speed(); err != nil || n != -1 {
+		t.Error("Wrong speed of link down", n, err)
+	}
+	sda, _ := u.DeviceFromSubsystemSysname("block", "sda")
+	if _, err := NewNetDevice(sda); err == nil {
+		t.Error("No error for block device")
+	}
+}
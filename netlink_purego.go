@@ -0,0 +1,201 @@
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"bytes"
+	"encoding/binary"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Netlink multicast groups of the uevent socket
+const (
+	netlinkGroupNone   = 0
+	netlinkGroupKernel = 1
+	netlinkGroupUdev   = 2
+)
+
+// Layout of the header udev prepends to the messages it sends, see struct monitor_netlink_header in libudev
+const (
+	udevMonitorMagic        = 0xfeedcafe
+	udevHeaderSize          = 40
+	udevHeaderMagic         = 8
+	udevHeaderPropertiesOff = 16
+	udevHeaderPropertiesLen = 20
+	udevHeaderSubsystemHash = 24
+	udevHeaderDevtypeHash   = 28
+	udevHeaderTagBloomHi    = 32
+	udevHeaderTagBloomLo    = 36
+)
+
+// maxFilterInstructions is the maximum size of the socket filter, as in libudev
+const maxFilterInstructions = 512
+
+// murmurHash2 returns the MurmurHash2 of a string with seed 0, used by udev to hash subsystems and devtypes.
+func murmurHash2(s string) uint32 {
+	const m = 0x5bd1e995
+	const r = 24
+	data := []byte(s)
+	h := uint32(len(data))
+	for len(data) >= 4 {
+		k := binary.NativeEndian.Uint32(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+		data = data[4:]
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// bloom64 returns the bits of a string in the 64 bit tag bloom filter used by udev.
+func bloom64(s string) uint64 {
+	h := murmurHash2(s)
+	return 1<<(h&63) | 1<<((h>>6)&63) | 1<<((h>>12)&63) | 1<<((h>>18)&63)
+}
+
+// newDeviceFromUevent creates a device from a message received on the uevent netlink socket.
+// Messages sent by udev start with a header, messages sent by the kernel with a "action@devpath" summary.
+func (u *Udev) newDeviceFromUevent(buf []byte) (*Device, error) {
+	var props []byte
+	fromUdev := bytes.HasPrefix(buf, []byte("libudev\x00"))
+	if fromUdev {
+		if len(buf) < udevHeaderSize || binary.BigEndian.Uint32(buf[udevHeaderMagic:]) != udevMonitorMagic {
+			return nil, syscall.EINVAL
+		}
+		off := int(binary.NativeEndian.Uint32(buf[udevHeaderPropertiesOff:]))
+		if off < udevHeaderSize || off >= len(buf) {
+			return nil, syscall.EINVAL
+		}
+		props = buf[off:]
+	} else {
+		i := bytes.IndexByte(buf, 0)
+		if i < len("a@/d") || !bytes.Contains(buf[:i], []byte("@/")) {
+			return nil, syscall.EINVAL
+		}
+		props = buf[i+1:]
+	}
+	d := u.newDevice("")
+	for _, p := range bytes.Split(props, []byte{0}) {
+		if i := bytes.IndexByte(p, '='); i > 0 {
+			d.setProperty(string(p[:i]), string(p[i+1:]))
+		}
+	}
+	if d.devpath == "" || d.subsystem == "" {
+		return nil, syscall.EINVAL
+	}
+	// Devices received from udev are always initialized
+	d.initialized = fromUdev
+	d.setListProperties()
+	return d, nil
+}
+
+func bpfStmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// socketFilter returns the socket filter program matching the udev message header against the monitor filters.
+// Messages without a udev header are passed, and filtered after they are received.
+func (m *Monitor) socketFilter() []unix.SockFilter {
+	ins := []unix.SockFilter{
+		// Load magic in A
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, udevHeaderMagic),
+		// Jump if magic matches
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, udevMonitorMagic, 1, 0),
+		// Wrong magic, pass packet
+		bpfStmt(unix.BPF_RET|unix.BPF_K, 0xffffffff),
+	}
+	if len(m.tagFilter) > 0 {
+		tagMatches := len(m.tagFilter)
+		for _, t := range m.tagFilter {
+			bits := bloom64(t)
+			hi, lo := uint32(bits>>32), uint32(bits)
+			tagMatches--
+			ins = append(ins,
+				// Load device bloom bits in A, and clear the bits not in the tag
+				bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, udevHeaderTagBloomHi),
+				bpfStmt(unix.BPF_ALU|unix.BPF_AND|unix.BPF_K, hi),
+				// Jump to next tag if it does not match
+				bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, hi, 0, 3),
+				bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, udevHeaderTagBloomLo),
+				bpfStmt(unix.BPF_ALU|unix.BPF_AND|unix.BPF_K, lo),
+				// Jump behind end of tag match block if tag matches
+				bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, lo, uint8(1+tagMatches*6), 0),
+			)
+		}
+		// Nothing matched, drop packet
+		ins = append(ins, bpfStmt(unix.BPF_RET|unix.BPF_K, 0))
+	}
+	if len(m.subsystemFilter) > 0 {
+		for _, f := range m.subsystemFilter {
+			// Load device subsystem hash in A
+			ins = append(ins, bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, udevHeaderSubsystemHash))
+			if f[1] == "" {
+				// Jump if subsystem does not match
+				ins = append(ins, bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, murmurHash2(f[0]), 0, 1))
+			} else {
+				ins = append(ins,
+					// Jump if subsystem does not match
+					bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, murmurHash2(f[0]), 0, 3),
+					// Load device devtype hash in A, and jump if it does not match
+					bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, udevHeaderDevtypeHash),
+					bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, murmurHash2(f[1]), 0, 1),
+				)
+			}
+			// Matched, pass packet
+			ins = append(ins, bpfStmt(unix.BPF_RET|unix.BPF_K, 0xffffffff))
+		}
+		// Nothing matched, drop packet
+		ins = append(ins, bpfStmt(unix.BPF_RET|unix.BPF_K, 0))
+	}
+	// Matched, pass packet
+	return append(ins, bpfStmt(unix.BPF_RET|unix.BPF_K, 0xffffffff))
+}
+
+// passesFilter checks a received device against the monitor filters.
+// The socket filter only uses hashes and ignores messages from the kernel, so the filters are checked again.
+func (m *Monitor) passesFilter(d *Device) bool {
+	if len(m.subsystemFilter) > 0 {
+		matched := false
+		for _, f := range m.subsystemFilter {
+			if f[0] == d.subsystem && (f[1] == "" || f[1] == d.devtype) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(m.tagFilter) == 0 {
+		return true
+	}
+	for _, t := range m.tagFilter {
+		if _, ok := d.tags[t]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestMurmurHash2(t *testing.T) {
+	hashes := map[string]uint32{
+		"":           0x00000000,
+		"block":      0xf0031db7,
+		"disk":       0x7bcbc5ee,
+		"systemd":    0xa75f972a,
+		"usb_device": 0x27f8f50c,
+	}
+	for s, h := range hashes {
+		if murmurHash2(s) != h {
+			t.Errorf("murmurHash2(%q) = %#x, want %#x", s, murmurHash2(s), h)
+		}
+	}
+	if bloom64("systemd") != 0x0200040010800000 {
+		t.Error("Wrong bloom bits for systemd")
+	}
+}
+
+func TestNewDeviceFromKernelUevent(t *testing.T) {
+	u := Udev{}
+	msg := "add@/devices/virtual/mem/zero\x00ACTION=add\x00DEVPATH=/devices/virtual/mem/zero\x00SUBSYSTEM=mem\x00MAJOR=1\x00MINOR=5\x00DEVNAME=zero\x00SEQNUM=1234\x00"
+	d, err := u.newDeviceFromUevent([]byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Action() != "add" || d.Seqnum() != 1234 {
+		t.Error("Wrong action or seqnum")
+	}
+	if d.Syspath() != "/sys/devices/virtual/mem/zero" || d.Sysname() != "zero" {
+		t.Error("Wrong syspath or sysname")
+	}
+	if d.Devnode() != "/dev/zero" || d.Devnum().Major() != 1 || d.Devnum().Minor() != 5 {
+		t.Error("Wrong devnode or devnum")
+	}
+	if d.IsInitialized() {
+		t.Error("Kernel devices should not be initialized")
+	}
+	if _, err := u.newDeviceFromUevent([]byte("garbage\x00SUBSYSTEM=mem\x00")); err == nil {
+		t.Error("Invalid message accepted")
+	}
+}
+
+func TestNewDeviceFromUdevUevent(t *testing.T) {
+	u := Udev{}
+	props := "ACTION=change\x00DEVPATH=/devices/virtual/block/loop0\x00SUBSYSTEM=block\x00DEVTYPE=disk\x00TAGS=:systemd:\x00DEVLINKS=/dev/disk/by-id/a /dev/disk/by-id/b\x00"
+	buf := make([]byte, udevHeaderSize, udevHeaderSize+len(props))
+	copy(buf, "libudev\x00")
+	binary.BigEndian.PutUint32(buf[udevHeaderMagic:], udevMonitorMagic)
+	binary.NativeEndian.PutUint32(buf[udevHeaderPropertiesOff:], udevHeaderSize)
+	binary.NativeEndian.PutUint32(buf[udevHeaderPropertiesLen:], uint32(len(props)))
+	buf = append(buf, props...)
+	d, err := u.newDeviceFromUevent(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.IsInitialized() || !d.HasTag("systemd") || len(d.Devlinks()) != 2 {
+		t.Error("Wrong udev device")
+	}
+	m := Monitor{u: &u}
+	m.subsystemFilter = [][2]string{{"block", "disk"}}
+	if !m.passesFilter(d) {
+		t.Error("Device should pass subsystem filter")
+	}
+	m.tagFilter = []string{"other"}
+	if m.passesFilter(d) {
+		t.Error("Device should not pass tag filter")
+	}
+}
@@ -0,0 +1,198 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DropPolicy decides what happens to a device when a channel of devices is full
+type DropPolicy int
+
+const (
+	// Block waits until the device is received, which also delays receiving from the socket
+	Block DropPolicy = iota
+	// DropNewest drops the device which does not fit into the channel
+	DropNewest
+	// DropOldest drops the oldest device in the channel to make room for the new one
+	DropOldest
+	// Coalesce replaces a device which was not received yet with a newer one of the same syspath,
+	// and otherwise waits until there is room like Block
+	Coalesce
+)
+
+// queueCounters counts the devices a deviceQueue dropped and coalesced, whatever the type of its devices
+type queueCounters struct {
+	dropped   atomic.Uint64
+	coalesced atomic.Uint64
+}
+
+// deviceQueue sends devices on a channel, handling a full channel according to a drop policy.
+// Devices are sent by a single goroutine, while the receiving side may abort the queue at any time.
+type deviceQueue[D DeviceInfo] struct {
+	ch     chan D
+	policy DropPolicy
+	queueCounters
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// Pending devices of the Coalesce policy, sent on the channel by the forward goroutine
+	mu       sync.Mutex
+	pending  []D
+	capacity int
+	closed   bool
+	notEmpty chan struct{}
+	notFull  chan struct{}
+}
+
+// newDeviceQueue returns a new queue for a channel with the capacity and policy given
+func newDeviceQueue[D DeviceInfo](capacity int, policy DropPolicy) *deviceQueue[D] {
+	q := &deviceQueue[D]{
+		policy: policy,
+		stop:   make(chan struct{}),
+	}
+	if policy != Coalesce {
+		q.ch = make(chan D, capacity)
+		return q
+	}
+	// Coalescing needs access to the devices not received yet, so these are kept in a slice instead
+	if capacity < 1 {
+		capacity = 1
+	}
+	q.ch = make(chan D)
+	q.capacity = capacity
+	q.notEmpty = make(chan struct{}, 1)
+	q.notFull = make(chan struct{}, 1)
+	go q.forward()
+	return q
+}
+
+// send sends a device according to the policy, and returns false if the context is done or the queue was aborted
+func (q *deviceQueue[D]) send(ctx context.Context, d D) bool {
+	switch q.policy {
+	case Block:
+		select {
+		case q.ch <- d:
+		case <-q.stop:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	case DropNewest:
+		select {
+		case q.ch <- d:
+		default:
+			q.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case q.ch <- d:
+				return true
+			default:
+			}
+			if cap(q.ch) == 0 {
+				// Without a buffer there is no oldest device to drop
+				q.dropped.Add(1)
+				return true
+			}
+			// The receiver may have received the oldest device in the meantime
+			select {
+			case <-q.ch:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	case Coalesce:
+		return q.push(ctx, d)
+	}
+	return true
+}
+
+// push adds a device to the pending devices, replacing a pending device with the same syspath
+func (q *deviceQueue[D]) push(ctx context.Context, d D) bool {
+	syspath := d.Syspath()
+	for {
+		q.mu.Lock()
+		for i, p := range q.pending {
+			if p.Syspath() == syspath {
+				q.pending[i] = d
+				q.mu.Unlock()
+				q.coalesced.Add(1)
+				return true
+			}
+		}
+		if len(q.pending) < q.capacity {
+			q.pending = append(q.pending, d)
+			q.mu.Unlock()
+			signal(q.notEmpty)
+			return true
+		}
+		q.mu.Unlock()
+		select {
+		case <-q.notFull:
+		case <-q.stop:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// forward sends the pending devices on the channel, until the queue is closed and empty or aborted
+func (q *deviceQueue[D]) forward() {
+	defer close(q.ch)
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			closed := q.closed
+			q.mu.Unlock()
+			if closed {
+				return
+			}
+			select {
+			case <-q.notEmpty:
+			case <-q.stop:
+				return
+			}
+			continue
+		}
+		d := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+		signal(q.notFull)
+		select {
+		case q.ch <- d:
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// close closes the channel once the devices in the queue were received, and must not be called concurrently with send
+func (q *deviceQueue[D]) close() {
+	if q.policy != Coalesce {
+		close(q.ch)
+		return
+	}
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	signal(q.notEmpty)
+}
+
+// abort stops a blocked send and drops the pending devices of the Coalesce policy, close still needs to be called
+func (q *deviceQueue[D]) abort() {
+	q.stopOnce.Do(func() { close(q.stop) })
+}
+
+// signal wakes up a goroutine waiting on a channel with a capacity of one, without blocking
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
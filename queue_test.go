@@ -0,0 +1,109 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func queueDevices(t *testing.T) (null, zero, random *Device) {
+	u := Udev{}
+	var err error
+	if null, err = u.DeviceFromSubsystemSysname("mem", "null"); err != nil {
+		t.Fatal(err)
+	}
+	if zero, err = u.DeviceFromSubsystemSysname("mem", "zero"); err != nil {
+		t.Fatal(err)
+	}
+	if random, err = u.DeviceFromSubsystemSysname("mem", "random"); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestDeviceQueuePolicies(t *testing.T) {
+	null, zero, random := queueDevices(t)
+	ctx := context.Background()
+	tests := []struct {
+		policy   DropPolicy
+		received []*Device
+	}{
+		{DropNewest, []*Device{null, zero}},
+		{DropOldest, []*Device{null, random}},
+	}
+	for _, test := range tests {
+		q := newDeviceQueue[*Device](2, test.policy)
+		for _, d := range []*Device{null, zero, null, random} {
+			if !q.send(ctx, d) {
+				t.Error("Send failed")
+			}
+		}
+		q.close()
+		var received []*Device
+		for d := range q.ch {
+			received = append(received, d)
+		}
+		if len(received) != len(test.received) {
+			t.Errorf("Policy %d received %d devices", test.policy, len(received))
+			continue
+		}
+		for i := range received {
+			if received[i] != test.received[i] {
+				t.Errorf("Policy %d received %s at %d", test.policy, received[i].Sysname(), i)
+			}
+		}
+		if q.dropped.Load() != 2 {
+			t.Errorf("Policy %d dropped %d", test.policy, q.dropped.Load())
+		}
+	}
+}
+
+func TestDeviceQueueCoalesce(t *testing.T) {
+	null, zero, _ := queueDevices(t)
+	q := newDeviceQueue[*Device](2, Coalesce)
+	for _, d := range []*Device{null, zero, null, null} {
+		if !q.send(context.Background(), d) {
+			t.Error("Send failed")
+		}
+	}
+	q.close()
+	var received []*Device
+	for d := range q.ch {
+		received = append(received, d)
+	}
+	// The first device may have been taken by the forwarding goroutine before it could be replaced
+	if uint64(len(received))+q.coalesced.Load() != 4 || q.coalesced.Load() == 0 {
+		t.Errorf("Received %d and coalesced %d devices", len(received), q.coalesced.Load())
+	}
+	// A replaced device keeps its position
+	n := 0
+	for _, d := range received {
+		if d == zero {
+			n++
+		}
+	}
+	if n != 1 || received[0] != null || q.dropped.Load() != 0 {
+		t.Error("Wrong devices received")
+	}
+}
+
+func TestDeviceQueueAbort(t *testing.T) {
+	null, zero, _ := queueDevices(t)
+	for _, policy := range []DropPolicy{Block, Coalesce} {
+		q := newDeviceQueue[*Device](1, policy)
+		go func() {
+			<-time.After(100 * time.Millisecond)
+			q.abort()
+		}()
+		// The second or third send blocks until aborted
+		ok := q.send(context.Background(), null) && q.send(context.Background(), zero) && q.send(context.Background(), null)
+		if ok {
+			t.Errorf("Policy %d did not block", policy)
+		}
+		q.close()
+		for range q.ch {
+		}
+	}
+}
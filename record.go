@@ -0,0 +1,250 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record holds a device received from a monitor, as written by a Recorder and read by a Replay
+type Record struct {
+	// Time is the time the device was recorded
+	Time        time.Time         `json:"time"`
+	Action      string            `json:"action"`
+	Seqnum      uint64            `json:"seqnum"`
+	Syspath     string            `json:"syspath"`
+	Initialized bool              `json:"initialized,omitempty"`
+	Properties  map[string]string `json:"properties"`
+	// Sysattrs holds the values of the sys attributes read when the device was recorded
+	Sysattrs map[string]string `json:"sysattrs,omitempty"`
+}
+
+// Recorder writes devices as JSON lines, one Record per line
+type Recorder struct {
+	// Sysattrs lists the sys attributes to record, all sys attributes of a device are recorded if nil
+	Sysattrs []string
+
+	m   sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder returns a pointer to a new recorder writing to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Record writes the device, with a snapshot of its sys attributes unless it was removed.
+// Any DeviceInfo can be recorded, like the devices of a Replay.
+// It is safe to call Record from multiple goroutines.
+func (r *Recorder) Record(d DeviceInfo) error {
+	rec := Record{
+		Time:        time.Now(),
+		Action:      d.Action(),
+		Seqnum:      d.Seqnum(),
+		Syspath:     d.Syspath(),
+		Initialized: d.IsInitialized(),
+		Properties:  d.Properties(),
+	}
+	// Removed devices no longer have any sys attributes
+	if rec.Action != "remove" {
+		rec.Sysattrs = make(map[string]string)
+		names := r.Sysattrs
+		if names == nil {
+			for name := range d.Sysattrs() {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			if v, ok := lookupSysattr(d, name); ok {
+				rec.Sysattrs[name] = v
+			}
+		}
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.enc.Encode(&rec)
+}
+
+// Replay is a source of devices read from the records written by a Recorder, sending them like a Monitor, see EventSource.
+// The devices replayed are in-memory devices holding the recorded properties and sys attributes, which never
+// read sysfs or the udev database: they have no parent, and sys attributes which were not recorded are missing.
+type Replay struct {
+	// Speed scales the timing of the records: 1 replays with the original timing, 2 twice as fast,
+	// and 0 replays the devices as fast as they are received
+	Speed float64
+
+	dec *json.Decoder
+}
+
+// NewReplay returns a pointer to a new replay reading the records from r
+func NewReplay(r io.Reader) *Replay {
+	return &Replay{dec: json.NewDecoder(r)}
+}
+
+// Next reads the next record and returns the device replayed from it, or io.EOF after the last record.
+// The timing of the records is ignored.
+func (r *Replay) Next() (*MemDevice, error) {
+	rec, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	return newReplayedDevice(rec), nil
+}
+
+// newReplayedDevice returns a pointer to a new in-memory device replayed from a record, without parent
+func newReplayedDevice(r *Record) *MemDevice {
+	m := NewMemDevice(nil, r.Properties, r.Sysattrs)
+	// The record holds the syspath and the event, which the properties may lack
+	m.s.Syspath = r.Syspath
+	m.s.Sysname, m.s.Sysnum = r.sysname(), r.sysnum()
+	m.s.Action, m.s.Seqnum, m.s.Initialized = r.Action, r.Seqnum, r.Initialized
+	return m
+}
+
+// next reads the next record, and returns io.EOF after the last record
+func (r *Replay) next() (*Record, error) {
+	rec := new(Record)
+	if err := r.dec.Decode(rec); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, &Error{Op: "json.Decode", Err: err}
+	}
+	return rec, nil
+}
+
+// DeviceChan spawns a goroutine sending the replayed devices on the returned channel, like Monitor.DeviceChan.
+// The function takes a context as argument, which when done will stop the goroutine and close the device channel.
+// The channel is also closed after the last record, or when a record can't be read.
+func (r *Replay) DeviceChan(ctx context.Context) (<-chan DeviceInfo, error) {
+	ch, _, e := r.DeviceInfoChan(ctx, ChanOptions{})
+	return ch, e
+}
+
+// DeviceErrChan is like DeviceChan, but also returns an error channel, on which the error
+// is sent when a record can't be read. Both channels are closed after the last record.
+// The error channel must be drained along with the device channel.
+func (r *Replay) DeviceErrChan(ctx context.Context) (<-chan DeviceInfo, <-chan error, error) {
+	return r.DeviceInfoChan(ctx, ChanOptions{Errors: true})
+}
+
+// DeviceInfoChan is like DeviceChan, but with a device channel of the capacity and drop policy given by
+// the options, see EventSource. The error channel is nil unless enabled by the options.
+func (r *Replay) DeviceInfoChan(ctx context.Context, opts ChanOptions) (<-chan DeviceInfo, <-chan error, error) {
+	q := newDeviceQueue[DeviceInfo](opts.Capacity, opts.Policy)
+	var errs chan error
+	if opts.Errors {
+		errs = make(chan error)
+	}
+	go func() {
+		// Close the channels when goroutine exits, dropping pending devices if the context is done
+		defer func() {
+			if ctx.Err() != nil {
+				q.abort()
+			}
+			q.close()
+			if errs != nil {
+				close(errs)
+			}
+		}()
+		var start, first time.Time
+		for {
+			// Check for done signal
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			rec, e := r.next()
+			if e == io.EOF {
+				return
+			}
+			if e != nil {
+				if errs != nil {
+					select {
+					case errs <- e:
+					case <-ctx.Done():
+					}
+				}
+				return
+			}
+			// Wait until the record is due, relative to the first record
+			if r.Speed > 0 {
+				if start.IsZero() {
+					start, first = time.Now(), rec.Time
+				}
+				due := start.Add(time.Duration(float64(rec.Time.Sub(first)) / r.Speed))
+				if wait := time.Until(due); wait > 0 {
+					t := time.NewTimer(wait)
+					select {
+					case <-t.C:
+					case <-ctx.Done():
+						t.Stop()
+						return
+					}
+				}
+			}
+			if !q.send(ctx, newReplayedDevice(rec)) {
+				return
+			}
+		}
+	}()
+	return q.ch, errs, nil
+}
+
+// sysname returns the sysname of a recorded device, where a '!' in sysfs represents a '/'
+func (r *Record) sysname() string {
+	return strings.Replace(filepath.Base(r.Syspath), "!", "/", -1)
+}
+
+// sysnum returns the trailing number of the sysname of a recorded device
+func (r *Record) sysnum() string {
+	s := r.sysname()
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	if i == 0 {
+		return ""
+	}
+	return s[i:]
+}
+
+// devnode returns the device node of a recorded device
+func (r *Record) devnode() string {
+	n := r.Properties["DEVNAME"]
+	if n != "" && !strings.HasPrefix(n, "/") {
+		n = "/dev/" + n
+	}
+	return n
+}
+
+// devnum returns the device number of a recorded device
+func (r *Record) devnum() Devnum {
+	major, _ := strconv.Atoi(r.Properties["MAJOR"])
+	minor, _ := strconv.Atoi(r.Properties["MINOR"])
+	return MkDev(major, minor)
+}
+
+// list returns the sorted entries of a DEVLINKS or TAGS property of a recorded device
+func (r *Record) list(key string) (l []string) {
+	sep := " "
+	if key == "TAGS" {
+		sep = ":"
+	}
+	for _, e := range strings.Split(r.Properties[key], sep) {
+		if e != "" {
+			l = append(l, e)
+		}
+	}
+	sort.Strings(l)
+	return
+}
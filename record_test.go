@@ -0,0 +1,177 @@
+// +build linux
+
+package udev
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ExampleRecorder() {
+
+	// Create Udev and Device
+	u := Udev{}
+	d, _ := u.DeviceFromSubsystemSysname("mem", "null")
+
+	// Record the device with its dev sys attribute, as done for the devices received from a monitor
+	var b bytes.Buffer
+	r := NewRecorder(&b)
+	r.Sysattrs = []string{"dev"}
+	r.Record(d)
+
+	// Read the device back
+	replayed, _ := NewReplay(&b).Next()
+	fmt.Println(replayed.Syspath(), replayed.SysattrValue("dev"))
+	// Output: /sys/devices/virtual/mem/null 1:3
+}
+
+func ExampleReplay() {
+
+	// Create a Replay, reading records written by a Recorder
+	r := NewReplay(strings.NewReader(`{"time":"2024-01-01T00:00:00Z","action":"add","seqnum":1,"syspath":"/sys/devices/virtual/block/loop0","properties":{}}
+{"time":"2024-01-01T00:00:00.1Z","action":"remove","seqnum":2,"syspath":"/sys/devices/virtual/block/loop0","properties":{}}
+`))
+
+	// Replay the devices twice as fast as they were recorded
+	r.Speed = 2
+	ch, _ := r.DeviceChan(context.Background())
+	for d := range ch {
+		fmt.Println(d.Syspath(), d.Action())
+	}
+	// Output:
+	// /sys/devices/virtual/block/loop0 add
+	// /sys/devices/virtual/block/loop0 remove
+}
+
+func TestRecorderReplay(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	r.Sysattrs = []string{"dev", "missing"}
+	if err := r.Record(d); err != nil {
+		t.Fatal(err)
+	}
+	// A recorded add event, written by hand
+	buf.WriteString(`{"time":"2024-01-01T00:00:00Z","action":"add","seqnum":42,"syspath":"/sys/devices/virtual/block/loop9",` +
+		`"initialized":true,"properties":{"ACTION":"add","DEVPATH":"/devices/virtual/block/loop9","SUBSYSTEM":"block",` +
+		`"DEVTYPE":"disk","DEVNAME":"loop9","MAJOR":"7","MINOR":"9","SEQNUM":"42","TAGS":":systemd:seat:",` +
+		`"DEVLINKS":"/dev/disk/by-id/b /dev/disk/by-id/a"},"sysattrs":{"size":"0"}}` + "\n")
+	p := NewReplay(&buf)
+	n, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Syspath() != d.Syspath() || n.Subsystem() != "mem" || n.Devnum() != d.Devnum() || n.ParentInfo() != nil {
+		t.Error("Recorded device differs")
+	}
+	if v, ok := n.lookupSysattr("dev"); !ok || v != "1:3" || len(n.Sysattrs()) != 1 {
+		t.Error("Recorded sys attributes differ")
+	}
+	l, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Sysname() != "loop9" || l.Sysnum() != "9" || l.Devpath() != "/devices/virtual/block/loop9" || l.Devtype() != "disk" {
+		t.Error("Identity of the replayed device differs")
+	}
+	if l.Action() != "add" || l.Seqnum() != 42 || !l.IsInitialized() || l.Devnode() != "/dev/loop9" || l.Devnum() != MkDev(7, 9) {
+		t.Error("Event of the replayed device differs")
+	}
+	if !l.HasTag("seat") || len(l.Tags()) != 2 || len(l.Devlinks()) != 2 || l.SysattrValue("size") != "0" || l.SysattrValue("dev") != "" {
+		t.Error("Lists of the replayed device differ")
+	}
+	if _, ok := l.Devlinks()["/dev/disk/by-id/a"]; !ok {
+		t.Error(l.Devlinks())
+	}
+	if _, err := p.Next(); err != io.EOF {
+		t.Error(err)
+	}
+	// Replayed devices can be recorded again
+	buf.Reset()
+	if err := NewRecorder(&buf).Record(l); err != nil {
+		t.Fatal(err)
+	}
+	if a, err := NewReplay(&buf).Next(); err != nil || !reflect.DeepEqual(a.Snapshot(), l.Snapshot()) {
+		t.Error("Device recorded again differs", err)
+	}
+}
+
+func TestReplayDeviceErrChan(t *testing.T) {
+	// records returns three records, spread over the duration given
+	records := func(d time.Duration) string {
+		var b strings.Builder
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, action := range []string{"add", "change", "remove"} {
+			fmt.Fprintf(&b, `{"time":%q,"action":%q,"seqnum":%d,"syspath":"/sys/devices/a","properties":{}}`+"\n",
+				start.Add(d*time.Duration(i)/2).Format(time.RFC3339Nano), action, i+1)
+		}
+		return b.String()
+	}
+	// Replays not scaled as expected take an hour, and are stopped by the context
+	for _, test := range []struct {
+		speed    float64
+		duration time.Duration
+		min      time.Duration
+	}{
+		{0, time.Hour, 0},
+		{1, 400 * time.Millisecond, 400 * time.Millisecond},
+		{4, 400 * time.Millisecond, 100 * time.Millisecond},
+		{36000, time.Hour, 100 * time.Millisecond},
+	} {
+		p := NewReplay(strings.NewReader(records(test.duration)))
+		p.Speed = test.speed
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		start := time.Now()
+		devices, errs, err := p.DeviceErrChan(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var seqnums []uint64
+		for devices != nil || errs != nil {
+			select {
+			case d, ok := <-devices:
+				if !ok {
+					devices = nil
+					continue
+				}
+				seqnums = append(seqnums, d.Seqnum())
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				t.Error(err)
+			}
+		}
+		cancel()
+		if elapsed := time.Since(start); elapsed < test.min {
+			t.Errorf("Speed %v replayed in %v", test.speed, elapsed)
+		}
+		if fmt.Sprint(seqnums) != "[1 2 3]" {
+			t.Errorf("Speed %v replayed %v", test.speed, seqnums)
+		}
+	}
+	// Invalid records are reported
+	p := NewReplay(strings.NewReader(records(0) + "{"))
+	devices, errs, err := p.DeviceErrChan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range devices {
+		}
+	}()
+	if err := <-errs; err == nil {
+		t.Error("No error reported")
+	}
+}
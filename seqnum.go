@@ -0,0 +1,117 @@
+// +build linux
+
+package udev
+
+import (
+	"fmt"
+	"time"
+)
+
+// udevReorderWindow is the time a missing udev event may arrive late, as udevd processes events in parallel
+// and broadcasts them out of order. Kernel events are broadcast in order and missing ones are lost immediately.
+const udevReorderWindow = 30 * time.Second
+
+// SeqnumGapError is sent on the error channel of DeviceErrChan when uevents were lost,
+// detected by a gap in the sequence numbers of the received events.
+// The events with sequence numbers from Expected up to, but not including, Received were not received.
+// errors.Is reports a SeqnumGapError as ErrOverrun.
+type SeqnumGapError struct {
+	// Source is the netlink source of the events, "kernel" or "udev"
+	Source string
+	// Expected is the sequence number of the first lost event
+	Expected uint64
+	// Received is the sequence number of the event received after the lost events
+	Received uint64
+}
+
+func (e *SeqnumGapError) Error() string {
+	if e.Received-e.Expected == 1 {
+		return fmt.Sprintf("udev: lost %s uevent with seqnum %d", e.Source, e.Expected)
+	}
+	return fmt.Sprintf("udev: lost %s uevents with seqnum %d to %d", e.Source, e.Expected, e.Received-1)
+}
+
+// Is reports a SeqnumGapError as ErrOverrun
+func (e *SeqnumGapError) Is(target error) bool {
+	return target == ErrOverrun
+}
+
+// seqnumRange is a range of missing sequence numbers, first to last inclusive, and when it went missing.
+type seqnumRange struct {
+	first, last uint64
+	since       time.Time
+}
+
+// seqnumTracker tracks the sequence numbers of the events received from a source to detect lost events.
+type seqnumTracker struct {
+	source  string
+	window  time.Duration
+	next    uint64
+	missing []seqnumRange
+}
+
+// newSeqnumTracker returns a tracker for the netlink source name, "kernel" or "udev".
+func newSeqnumTracker(name string) *seqnumTracker {
+	t := &seqnumTracker{source: name}
+	if name != "kernel" {
+		t.window = udevReorderWindow
+	}
+	return t
+}
+
+// reset forgets all sequence numbers, e.g. while a filter drops events
+func (t *seqnumTracker) reset() {
+	t.next = 0
+	t.missing = nil
+}
+
+// add records a received sequence number and returns the gaps which are known to be lost at the time now.
+func (t *seqnumTracker) add(seqnum uint64, now time.Time) []*SeqnumGapError {
+	switch {
+	case seqnum == 0:
+		// Events without a sequence number can't be tracked
+	case t.next == 0:
+		t.next = seqnum + 1
+	case seqnum >= t.next:
+		if seqnum > t.next {
+			t.missing = append(t.missing, seqnumRange{t.next, seqnum - 1, now})
+		}
+		t.next = seqnum + 1
+	default:
+		// A late event fills its place in a range of missing sequence numbers
+		for i, r := range t.missing {
+			if seqnum < r.first || seqnum > r.last {
+				continue
+			}
+			switch {
+			case r.first == r.last:
+				t.missing = append(t.missing[:i], t.missing[i+1:]...)
+			case seqnum == r.first:
+				t.missing[i].first++
+			case seqnum == r.last:
+				t.missing[i].last--
+			default:
+				t.missing = append(t.missing[:i+1], t.missing[i:]...)
+				t.missing[i].last = seqnum - 1
+				t.missing[i+1].first = seqnum + 1
+			}
+			break
+		}
+	}
+	return t.expire(now)
+}
+
+// expire returns the gaps which have been missing for longer than the reorder window at the time now.
+func (t *seqnumTracker) expire(now time.Time) (gaps []*SeqnumGapError) {
+	i := 0
+	for _, r := range t.missing {
+		if now.Sub(r.since) >= t.window {
+			gaps = append(gaps, &SeqnumGapError{Source: t.source, Expected: r.first, Received: r.last + 1})
+		} else {
+			t.missing[i] = r
+			i++
+		}
+	}
+	t.missing = t.missing[:i]
+	return
+}
@@ -0,0 +1,79 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSeqnumTrackerKernel(t *testing.T) {
+	tr := newSeqnumTracker("kernel")
+	now := time.Now()
+	if gaps := tr.add(10, now); len(gaps) != 0 {
+		t.Error("Gap on first event")
+	}
+	if gaps := tr.add(11, now); len(gaps) != 0 {
+		t.Error("Gap on consecutive event")
+	}
+	gaps := tr.add(15, now)
+	if len(gaps) != 1 || gaps[0].Expected != 12 || gaps[0].Received != 15 || gaps[0].Source != "kernel" {
+		t.Errorf("Wrong gaps %v", gaps)
+	}
+	if !errors.Is(gaps[0], ErrOverrun) {
+		t.Error("Gap is not an overrun")
+	}
+	if gaps[0].Error() != "udev: lost kernel uevents with seqnum 12 to 14" {
+		t.Error(gaps[0].Error())
+	}
+	// Events without a sequence number are ignored
+	if gaps := tr.add(0, now); len(gaps) != 0 {
+		t.Error("Gap on event without seqnum")
+	}
+	if gaps := tr.add(16, now); len(gaps) != 0 {
+		t.Error("Gap on consecutive event")
+	}
+}
+
+func TestSeqnumTrackerUdev(t *testing.T) {
+	tr := newSeqnumTracker("udev")
+	now := time.Now()
+	tr.add(1, now)
+	// 2 to 6 are missing, but may arrive late
+	if gaps := tr.add(7, now); len(gaps) != 0 {
+		t.Error("Gap reported within reorder window")
+	}
+	tr.add(2, now)
+	tr.add(4, now)
+	tr.add(6, now)
+	if gaps := tr.expire(now.Add(udevReorderWindow / 2)); len(gaps) != 0 {
+		t.Error("Gap reported within reorder window")
+	}
+	// 3 and 5 never arrived
+	gaps := tr.expire(now.Add(udevReorderWindow))
+	if len(gaps) != 2 {
+		t.Fatalf("Wrong gaps %v", gaps)
+	}
+	if gaps[0].Expected != 3 || gaps[0].Received != 4 || gaps[1].Expected != 5 || gaps[1].Received != 6 {
+		t.Errorf("Wrong gaps %v", gaps)
+	}
+	if gaps[0].Error() != "udev: lost udev uevent with seqnum 3" {
+		t.Error(gaps[0].Error())
+	}
+	if gaps := tr.expire(now.Add(2 * udevReorderWindow)); len(gaps) != 0 {
+		t.Error("Gap reported twice")
+	}
+	// A gap filled completely is not reported
+	tr.add(9, now)
+	tr.add(8, now)
+	if gaps := tr.expire(now.Add(udevReorderWindow)); len(gaps) != 0 {
+		t.Error("Filled gap reported")
+	}
+	// Reset forgets missing events
+	tr.add(12, now)
+	tr.reset()
+	if gaps := tr.expire(now.Add(udevReorderWindow)); len(gaps) != 0 {
+		t.Error("Gap reported after reset")
+	}
+}
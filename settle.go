@@ -0,0 +1,53 @@
+// +build linux,cgo,!purego
+
+package udev
+
+/*
+  #cgo LDFLAGS: -ludev
+  #include <libudev.h>
+*/
+import "C"
+import (
+	"context"
+	"syscall"
+)
+
+// Settle waits until the udev event queue is empty, like udevadm settle, or until the context is done.
+// It returns immediately if udevd is not running.
+func (u *Udev) Settle(ctx context.Context) error {
+	u.lock()
+	q, errno := C.udev_queue_new(u.ptr)
+	u.unlock()
+	if q == nil {
+		return newError("udev_queue_new", "", errno)
+	}
+	defer func() {
+		u.lock()
+		C.udev_queue_unref(q)
+		u.unlock()
+	}()
+	isEmpty := func() bool {
+		u.lock()
+		defer u.unlock()
+		return C.udev_queue_get_queue_is_empty(q) != 0
+	}
+	if isEmpty() {
+		return nil
+	}
+	// The queue fd becomes readable when the queue indicator file is removed
+	u.lock()
+	fd := C.udev_queue_get_fd(q)
+	u.unlock()
+	if fd < 0 {
+		return newError("udev_queue_get_fd", "", syscall.Errno(-fd))
+	}
+	for !isEmpty() {
+		if err := waitReadable(ctx, int(fd)); err != nil {
+			return err
+		}
+		u.lock()
+		C.udev_queue_flush(q)
+		u.unlock()
+	}
+	return nil
+}
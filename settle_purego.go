@@ -0,0 +1,49 @@
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// Settle waits until the udev event queue is empty, like udevadm settle, or until the context is done.
+// It returns immediately if udevd is not running.
+func (u *Udev) Settle(ctx context.Context) error {
+	// udevd creates the queue indicator file while it has events queued, and removes it when the queue is empty
+	queue := filepath.Join(u.udevRun(), "queue")
+	isEmpty := func() bool {
+		_, err := os.Lstat(queue)
+		return errors.Is(err, fs.ErrNotExist)
+	}
+	if isEmpty() {
+		return nil
+	}
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return newError("inotify_init1", "", err)
+	}
+	defer unix.Close(fd)
+	if _, err := unix.InotifyAddWatch(fd, u.udevRun(), unix.IN_DELETE|unix.IN_MOVED_FROM); err != nil {
+		return newError("inotify_add_watch", u.udevRun(), err)
+	}
+	var buf [4096]byte
+	for !isEmpty() {
+		if err := waitReadable(ctx, fd); err != nil {
+			return err
+		}
+		// Drain the events, only the state of the queue indicator file matters
+		for {
+			if _, err := unix.Read(fd, buf[:]); err != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+)
+
+func ExampleUdev_WaitForDevice() {
+
+	// Create Udev
+	u := Udev{}
+
+	// Wait up to 10 seconds for udev to set up a partition with an ext4 file system
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	u.WaitForDevice(ctx, Filter{
+		Subsystems: []string{"block"},
+		Devtype:    "partition",
+		Properties: map[string]string{"ID_FS_TYPE": "ext4"},
+	})
+}
+
+func TestSettle(t *testing.T) {
+	u := Udev{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := u.Settle(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSettleQueue(t *testing.T) {
+	// The queue indicator file of a running udevd must not be touched
+	if _, err := os.Stat("/run/udev/control"); err == nil {
+		t.Skip("udevd is running")
+	}
+	if _, err := os.Stat("/run/udev"); errors.Is(err, fs.ErrNotExist) {
+		if err := os.Mkdir("/run/udev", 0755); err != nil {
+			t.Skip(err)
+		}
+		defer os.Remove("/run/udev")
+	}
+	if err := os.WriteFile("/run/udev/queue", nil, 0644); err != nil {
+		t.Skip(err)
+	}
+	defer os.Remove("/run/udev/queue")
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.Remove("/run/udev/queue")
+	}()
+	u := Udev{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := u.Settle(ctx); err != nil || time.Since(start) < 200*time.Millisecond {
+		t.Error("Queue not waited for", err)
+	}
+}
+
+func TestWaitForDevice(t *testing.T) {
+	u := Udev{}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if d, err := u.WaitForDevice(ctx, Filter{Subsystems: []string{"mem"}, Sysnames: []string{"missing"}}); d != nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Missing device found", err)
+	}
+}
+
+func TestFakeSysfsSettle(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	f.write("run/udev/queue", "")
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.Remove(f.path("run/udev/queue"))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := u.Settle(ctx); err != nil || time.Since(start) < 200*time.Millisecond {
+		t.Error("Queue not waited for", err)
+	}
+	f.write("run/udev/queue", "")
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := u.Settle(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Queue not waited for", err)
+	}
+	// The partition is initialized already
+	d, err := u.WaitForDevice(context.Background(), Filter{Subsystems: []string{"block"}, Properties: map[string]string{"ID_FS_TYPE": "ext4"}})
+	if err != nil || d.Sysname() != "sda1" {
+		t.Error("Device not found", err)
+	}
+}
@@ -0,0 +1,128 @@
+// +build linux
+
+package udev
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Snapshot holds the state of a device captured at once by Device.Snapshot.
+// It is a plain value which does not refer to the device, and can be kept, compared and shared between goroutines.
+type Snapshot struct {
+	Syspath     string `json:"syspath"`
+	Devpath     string `json:"devpath"`
+	Subsystem   string `json:"subsystem"`
+	Devtype     string `json:"devtype"`
+	Sysname     string `json:"sysname"`
+	Sysnum      string `json:"sysnum"`
+	Devnode     string `json:"devnode"`
+	Driver      string `json:"driver"`
+	Devnum      Devnum `json:"devnum"`
+	Action      string `json:"action"`
+	Seqnum      uint64 `json:"seqnum"`
+	Initialized bool   `json:"initialized"`
+	// DevlinkPriority is the priority of the device links, which is only known for snapshots decoded by an ExportDecoder
+	DevlinkPriority int               `json:"devlinkPriority"`
+	Properties      map[string]string `json:"properties"`
+	// Tags, CurrentTags and Devlinks are sorted
+	Tags        []string `json:"tags"`
+	CurrentTags []string `json:"currentTags"`
+	Devlinks    []string `json:"devlinks"`
+	// Sysattrs holds the values of the sys attributes selected when taking the snapshot
+	Sysattrs map[string]string `json:"sysattrs"`
+	// Parent is the snapshot of the parent device, or nil if the device has no parent
+	Parent *Snapshot `json:"parent,omitempty"`
+}
+
+// Snapshot captures the state of the device and of its parents, with the values of the sys attributes named.
+// Sys attributes which the device does not have are left out.
+// The values are read one at a time, so the snapshot is not atomic: sys attributes or parents changing
+// meanwhile may be captured in different states.
+func (d *Device) Snapshot(sysattrs ...string) Snapshot {
+	s := Snapshot{
+		Syspath:     d.Syspath(),
+		Devpath:     d.Devpath(),
+		Subsystem:   d.Subsystem(),
+		Devtype:     d.Devtype(),
+		Sysname:     d.Sysname(),
+		Sysnum:      d.Sysnum(),
+		Devnode:     d.Devnode(),
+		Driver:      d.Driver(),
+		Devnum:      d.Devnum(),
+		Action:      d.Action(),
+		Seqnum:      d.Seqnum(),
+		Initialized: d.IsInitialized(),
+		Properties:  d.Properties(),
+		Tags:        slices.Sorted(maps.Keys(d.Tags())),
+		CurrentTags: slices.Sorted(maps.Keys(d.CurrentTags())),
+		Devlinks:    slices.Sorted(maps.Keys(d.Devlinks())),
+		Sysattrs:    make(map[string]string),
+	}
+	for _, name := range sysattrs {
+		if v, ok := d.lookupSysattr(name); ok {
+			s.Sysattrs[name] = v
+		}
+	}
+	if p := d.Parent(); p != nil {
+		ps := p.Snapshot(sysattrs...)
+		s.Parent = &ps
+	}
+	return s
+}
+
+// clone returns a copy of the snapshot sharing no lists or maps with it, without its parent
+func (s *Snapshot) clone() Snapshot {
+	c := *s
+	c.Parent = nil
+	c.Properties = maps.Clone(s.Properties)
+	c.Sysattrs = maps.Clone(s.Sysattrs)
+	c.Tags = slices.Clone(s.Tags)
+	c.CurrentTags = slices.Clone(s.CurrentTags)
+	c.Devlinks = slices.Clone(s.Devlinks)
+	return c
+}
+
+// currentTags returns the sorted tags of the CURRENT_TAGS property, or all tags if
+// udev does not distinguish the tags still applying to a device from sticky tags
+func currentTags(properties map[string]string, tags []string) []string {
+	v, ok := properties["CURRENT_TAGS"]
+	if !ok {
+		return slices.Clone(tags)
+	}
+	var r []string
+	for _, t := range strings.Split(v, ":") {
+		if t != "" {
+			r = append(r, t)
+		}
+	}
+	slices.Sort(r)
+	return r
+}
+
+// Equal reports whether two snapshots hold the same values, including those of their parents
+func (s *Snapshot) Equal(o *Snapshot) bool {
+	if s == nil || o == nil {
+		return s == o
+	}
+	return s.Syspath == o.Syspath &&
+		s.Devpath == o.Devpath &&
+		s.Subsystem == o.Subsystem &&
+		s.Devtype == o.Devtype &&
+		s.Sysname == o.Sysname &&
+		s.Sysnum == o.Sysnum &&
+		s.Devnode == o.Devnode &&
+		s.Driver == o.Driver &&
+		s.Devnum == o.Devnum &&
+		s.Action == o.Action &&
+		s.Seqnum == o.Seqnum &&
+		s.Initialized == o.Initialized &&
+		s.DevlinkPriority == o.DevlinkPriority &&
+		maps.Equal(s.Properties, o.Properties) &&
+		slices.Equal(s.Tags, o.Tags) &&
+		slices.Equal(s.CurrentTags, o.CurrentTags) &&
+		slices.Equal(s.Devlinks, o.Devlinks) &&
+		maps.Equal(s.Sysattrs, o.Sysattrs) &&
+		s.Parent.Equal(o.Parent)
+}
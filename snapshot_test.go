@@ -0,0 +1,121 @@
+// +build linux
+
+package udev
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func ExampleDevice_Snapshot() {
+
+	// Create Udev and Device
+	u := Udev{}
+	d := u.NewDeviceFromSyspath("/sys/devices/virtual/mem/null")
+
+	// Capture the device with its dev sys attribute
+	s := d.Snapshot("dev")
+	fmt.Println(s.Sysname, s.Subsystem, s.Sysattrs["dev"])
+	// Output: null mem 1:3
+}
+
+func TestDeviceSnapshot(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.Snapshot("dev", "missing")
+	if s.Syspath != d.Syspath() || s.Devnum != d.Devnum() || s.Devnode != "/dev/null" || s.Parent != nil {
+		t.Error("Wrong identity", s)
+	}
+	if len(s.Sysattrs) != 1 || s.Properties["SUBSYSTEM"] != "mem" {
+		t.Error("Wrong values", s.Sysattrs, s.Properties)
+	}
+	// Snapshots of the same device are equal
+	o := d.Snapshot("dev")
+	if !s.Equal(&o) {
+		t.Error("Snapshots differ")
+	}
+	o.Properties["SUBSYSTEM"] = "block"
+	if s.Equal(&o) || s.Properties["SUBSYSTEM"] != "mem" {
+		t.Error("Snapshots share properties")
+	}
+	z, err := u.DeviceFromSubsystemSysname("mem", "zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zs := z.Snapshot("dev"); s.Equal(&zs) {
+		t.Error("Snapshots of different devices are equal")
+	}
+}
+
+func TestDeviceSnapshotParents(t *testing.T) {
+	u := Udev{}
+	e := u.NewEnumerate()
+	devices, err := e.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range devices {
+		if d.Parent() == nil {
+			continue
+		}
+		s := d.Snapshot("uevent")
+		// The parent chain is captured like it is walked
+		for p := d.Parent(); p != nil; p = p.Parent() {
+			if s.Parent == nil || s.Parent.Syspath != p.Syspath() || s.Parent.Subsystem != p.Subsystem() || s.Parent.Driver != p.Driver() {
+				t.Fatalf("Wrong parent of %s", s.Syspath)
+			}
+			s = *s.Parent
+		}
+		if s.Parent != nil {
+			t.Errorf("Extra parent %s", s.Parent.Syspath)
+		}
+		return
+	}
+	t.Skip("no device with a parent")
+}
+
+func TestDeviceSnapshotCurrentTags(t *testing.T) {
+	records := `{"time":"2024-01-01T00:00:00Z","action":"add","seqnum":1,"syspath":"/sys/devices/a",` +
+		`"properties":{"DEVPATH":"/devices/a","TAGS":":b:a:","CURRENT_TAGS":":b:"}}
+{"time":"2024-01-01T00:00:00Z","action":"add","seqnum":2,"syspath":"/sys/devices/b","properties":{"DEVPATH":"/devices/b","TAGS":":b:a:"}}
+`
+	p := NewReplay(strings.NewReader(records))
+	for _, want := range []string{"[b]", "[a b]"} {
+		d, err := p.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := d.Snapshot()
+		if fmt.Sprint(s.Tags) != "[a b]" || fmt.Sprint(s.CurrentTags) != want {
+			t.Error("Wrong tags", s.Tags, s.CurrentTags)
+		}
+	}
+}
+
+func TestFakeSysfsSnapshot(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	d, err := u.DeviceFromSubsystemSysname("block", "sda1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.Snapshot("size", "vendor")
+	if fmt.Sprint(s.Tags) != "[systemd uaccess]" || fmt.Sprint(s.CurrentTags) != "[systemd]" || s.Properties["CURRENT_TAGS"] != ":systemd:" {
+		t.Error("Wrong tags", s.Tags, s.CurrentTags)
+	}
+	if s.Sysattrs["size"] != "1024" || s.Parent == nil || s.Parent.Sysattrs["size"] != "2048" {
+		t.Fatal("Wrong parent")
+	}
+	// Without current tags in the database, all tags are current
+	if fmt.Sprint(s.Parent.CurrentTags) != "[systemd]" {
+		t.Error("Wrong current tags of parent", s.Parent.CurrentTags)
+	}
+	pci := s.Parent.Parent
+	if pci == nil || pci.Sysattrs["vendor"] != "0x8086" || pci.Driver != "ahci" || pci.Parent != nil {
+		t.Error("Wrong parent chain")
+	}
+}
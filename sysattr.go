@@ -0,0 +1,52 @@
+// +build linux
+
+package udev
+
+import (
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// sysattrString returns the value of a sys attribute of the device, or an *Error for the operation if it has no such attribute
+func sysattrString(d *Device, op, sysattr string) (string, error) {
+	v, ok := d.lookupSysattr(sysattr)
+	if !ok {
+		return "", newError(op, d.Syspath()+"/"+sysattr, syscall.ENOENT)
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// sysattrUint parses a sys attribute of the device as an unsigned integer of the base and bit size given, see strconv.ParseUint,
+// or returns an *Error for the operation if it is missing or can't be parsed
+func sysattrUint(d *Device, op, sysattr string, base, bitSize int) (uint64, error) {
+	v, err := sysattrString(d, op, sysattr)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(v, base, bitSize)
+	if err != nil {
+		return 0, newError(op, d.Syspath()+"/"+sysattr, err)
+	}
+	return n, nil
+}
+
+// sysattrInt parses a sys attribute of the device as a decimal integer,
+// or returns an *Error for the operation if it is missing or can't be parsed
+func sysattrInt(d *Device, op, sysattr string) (int, error) {
+	v, err := sysattrString(d, op, sysattr)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, newError(op, d.Syspath()+"/"+sysattr, err)
+	}
+	return n, nil
+}
+
+// sysattrBool parses a sys attribute of the device holding 0 or 1
+func sysattrBool(d *Device, op, sysattr string) (bool, error) {
+	n, err := sysattrUint(d, op, sysattr, 10, 1)
+	return n == 1, err
+}
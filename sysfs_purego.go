@@ -0,0 +1,290 @@
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// setSyspath sets the syspath of the device and the fields derived from it.
+func (d *Device) setSyspath(syspath string) {
+	d.syspath = syspath
+	d.devpath = strings.TrimPrefix(syspath, d.u.sysfs())
+	// A '/' in a sysname is represented by a '!' in sysfs
+	d.sysname = strings.Replace(filepath.Base(syspath), "!", "/", -1)
+	// The sysnum is the trailing number of the sysname
+	i := len(d.sysname)
+	for i > 0 && d.sysname[i-1] >= '0' && d.sysname[i-1] <= '9' {
+		i--
+	}
+	d.sysnum = ""
+	if i > 0 {
+		d.sysnum = d.sysname[i:]
+	}
+}
+
+// setProperty sets a device property and updates the device fields derived from it.
+func (d *Device) setProperty(key, value string) {
+	switch key {
+	case "DEVPATH":
+		d.setSyspath(d.u.sysfs() + value)
+	case "SUBSYSTEM":
+		d.subsystem = value
+	case "DEVTYPE":
+		d.devtype = value
+	case "DRIVER":
+		d.driver = value
+	case "DEVNAME":
+		if !strings.HasPrefix(value, "/") {
+			value = devPath + "/" + value
+		}
+		d.devnode = value
+	case "MAJOR":
+		if major, err := strconv.Atoi(value); err == nil {
+			d.devnum = MkDev(major, d.devnum.Minor())
+		}
+	case "MINOR":
+		if minor, err := strconv.Atoi(value); err == nil {
+			d.devnum = MkDev(d.devnum.Major(), minor)
+		}
+	case "IFINDEX":
+		d.ifindex, _ = strconv.Atoi(value)
+	case "ACTION":
+		d.action = value
+	case "SEQNUM":
+		d.seqnum, _ = strconv.ParseUint(value, 10, 64)
+	case "USEC_INITIALIZED":
+		d.usecInitialized, _ = strconv.ParseUint(value, 10, 64)
+	case "DEVLINKS":
+		for _, l := range strings.Fields(value) {
+			d.devlinks[l] = struct{}{}
+		}
+	case "TAGS":
+		for _, t := range strings.Split(value, ":") {
+			if t != "" {
+				d.tags[t] = struct{}{}
+			}
+		}
+	case "CURRENT_TAGS":
+		for _, t := range strings.Split(value, ":") {
+			if t != "" {
+				d.addCurrentTag(t)
+			}
+		}
+	}
+	d.properties[key] = value
+}
+
+// addCurrentTag adds a tag still applying to the device.
+func (d *Device) addCurrentTag(tag string) {
+	if d.currentTags == nil {
+		d.currentTags = make(map[string]struct{})
+	}
+	d.currentTags[tag] = struct{}{}
+}
+
+// setListProperties sets the DEVLINKS, TAGS and CURRENT_TAGS properties from the devlinks and tags of the device.
+func (d *Device) setListProperties() {
+	if len(d.devlinks) > 0 {
+		d.properties["DEVLINKS"] = strings.Join(sortedKeys(d.devlinks), " ")
+	}
+	if len(d.tags) > 0 {
+		d.properties["TAGS"] = ":" + strings.Join(sortedKeys(d.tags), ":") + ":"
+	}
+	if len(d.currentTags) > 0 {
+		d.properties["CURRENT_TAGS"] = ":" + strings.Join(sortedKeys(d.currentTags), ":") + ":"
+	}
+}
+
+// readSysfs reads the subsystem, driver and uevent file of the device from sysfs.
+func (d *Device) readSysfs() error {
+	if link, err := os.Readlink(filepath.Join(d.syspath, "subsystem")); err == nil {
+		d.setProperty("SUBSYSTEM", filepath.Base(link))
+	} else {
+		// Devices without a subsystem link are modules, drivers or subsystems
+		switch {
+		case strings.HasPrefix(d.devpath, "/module/"):
+			d.setProperty("SUBSYSTEM", "module")
+		case strings.Contains(d.devpath, "/drivers/"):
+			d.setProperty("SUBSYSTEM", "drivers")
+		case strings.HasPrefix(d.devpath, "/subsystem/"),
+			strings.HasPrefix(d.devpath, "/class/"),
+			strings.HasPrefix(d.devpath, "/bus/"):
+			d.setProperty("SUBSYSTEM", "subsystem")
+		}
+	}
+	d.setProperty("DEVPATH", d.devpath)
+	if link, err := os.Readlink(filepath.Join(d.syspath, "driver")); err == nil {
+		d.setProperty("DRIVER", filepath.Base(link))
+	}
+	b, err := os.ReadFile(filepath.Join(d.syspath, "uevent"))
+	if err != nil {
+		// Modules, drivers and subsystems may not have an uevent file, or a write-only one
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			return nil
+		}
+		return err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if i := strings.IndexByte(line, '='); i > 0 {
+			d.setProperty(line[:i], line[i+1:])
+		}
+	}
+	return nil
+}
+
+// deviceID returns the id of the device in the udev database.
+func (d *Device) deviceID() string {
+	switch {
+	case d.devnum.Major() > 0:
+		t := 'c'
+		if d.subsystem == "block" {
+			t = 'b'
+		}
+		return fmt.Sprintf("%c%d:%d", t, d.devnum.Major(), d.devnum.Minor())
+	case d.ifindex > 0:
+		return fmt.Sprintf("n%d", d.ifindex)
+	case d.subsystem == "drivers":
+		// The subsystem of the driver is the name of the bus directory
+		return fmt.Sprintf("+drivers:%s:%s", filepath.Base(filepath.Dir(filepath.Dir(d.syspath))), d.sysname)
+	}
+	return fmt.Sprintf("+%s:%s", d.subsystem, d.sysname)
+}
+
+// readDB reads the entry of the device in the udev database.
+func (d *Device) readDB() error {
+	b, err := os.ReadFile(filepath.Join(d.u.udevRun(), "data", d.deviceID()))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Only devices with a device node or network interfaces need to be initialized by udev
+			d.initialized = d.devnum.Major() == 0 && d.ifindex == 0
+			return nil
+		}
+		return err
+	}
+	// Devices with a database entry are initialized
+	d.initialized = true
+	for _, line := range strings.Split(string(b), "\n") {
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		value := line[2:]
+		switch line[0] {
+		case 'S':
+			d.devlinks[devPath+"/"+value] = struct{}{}
+		case 'E':
+			if i := strings.IndexByte(value, '='); i > 0 {
+				d.setProperty(value[:i], value[i+1:])
+			}
+		case 'G':
+			d.tags[value] = struct{}{}
+		case 'Q':
+			d.addCurrentTag(value)
+		case 'I':
+			d.setProperty("USEC_INITIALIZED", value)
+		}
+	}
+	d.setListProperties()
+	return nil
+}
+
+// sysattrNames returns the sorted names of the readable sys attributes of the device.
+func (d *Device) sysattrNames() (r []string) {
+	entries, err := os.ReadDir(d.syspath)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		// Only handle symlinks and regular files
+		if e.Type()&os.ModeSymlink == 0 && !e.Type().IsRegular() {
+			continue
+		}
+		if fi, err := os.Lstat(filepath.Join(d.syspath, name)); err != nil || fi.Mode().Perm()&0400 == 0 {
+			continue
+		}
+		r = append(r, name)
+	}
+	sort.Strings(r)
+	return
+}
+
+// sysattrValue returns the value of a sys attribute and whether it could be read.
+// The value read is cached in the device.
+func (d *Device) sysattrValue(sysattr string) (string, bool) {
+	if v, ok := d.sysattrs[sysattr]; ok {
+		return v, true
+	}
+	path := filepath.Join(d.syspath, sysattr)
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return "", false
+	}
+	var v string
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		// Some links are returned as the last element of the link target
+		switch sysattr {
+		case "driver", "subsystem", "module":
+		default:
+			return "", false
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", false
+		}
+		v = filepath.Base(link)
+	case fi.IsDir():
+		return "", false
+	case fi.Mode().Perm()&0400 == 0:
+		return "", false
+	default:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", false
+		}
+		v = strings.TrimRight(string(b), "\n")
+	}
+	d.sysattrs[sysattr] = v
+	return v, true
+}
+
+// parent returns the parent device, reading it from sysfs on first use.
+func (d *Device) parent() *Device {
+	if d.parentRead {
+		return d.parentDevice
+	}
+	d.parentRead = true
+	// Walk up the syspath until a directory is a device, but not up to the top level directories of sysfs
+	rel := strings.TrimPrefix(d.syspath, d.u.sysfs()+"/")
+	for i := strings.LastIndexByte(rel, '/'); i > 0; i = strings.LastIndexByte(rel, '/') {
+		rel = rel[:i]
+		if !strings.Contains(rel, "/") {
+			break
+		}
+		if p, err := d.u.newDeviceFromSyspath(d.u.sysfs() + "/" + rel); err == nil {
+			d.parentDevice = p
+			break
+		}
+	}
+	return d.parentDevice
+}
+
+// sortedKeys returns the keys of a set in sorted order.
+func sortedKeys(m map[string]struct{}) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,110 @@
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"strings"
+	"testing"
+)
+
+// udev returns a udev context reading the fake sysfs tree and udev database
+func (f *fakeSysfs) udev() *Udev {
+	return &Udev{root: f.root}
+}
+
+// withoutCurrentTags skips the test, as the current tags are read from the udev database
+func withoutCurrentTags(t *testing.T) {
+	t.Helper()
+	t.Skip("libudev is only used by the cgo backend")
+}
+
+func TestFakeSysfsDevice(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	sda1, err := u.DeviceFromDevnum('b', MkDev(8, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sda1.Sysname() != "sda1" || sda1.Sysnum() != "1" || sda1.Subsystem() != "block" || sda1.Devtype() != "partition" {
+		t.Error("Wrong identity", sda1.Sysname(), sda1.Subsystem(), sda1.Devtype())
+	}
+	if sda1.Devpath() != "/devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1" || sda1.Devnode() != "/dev/sda1" {
+		t.Error("Wrong paths", sda1.Devpath(), sda1.Devnode())
+	}
+	if !sda1.IsInitialized() || sda1.PropertyValue("ID_FS_TYPE") != "ext4" || !sda1.HasTag("systemd") || len(sda1.Devlinks()) != 2 {
+		t.Error("Database entry not read", sda1.Properties())
+	}
+	if sda1.SysattrValue("partition") != "1" || len(sda1.Sysattrs()) != 4 {
+		t.Error("Sys attributes not read", sda1.Sysattrs())
+	}
+	// Directories without an uevent file are no devices
+	sda := sda1.Parent()
+	if sda == nil || sda.Sysname() != "sda" {
+		t.Fatal("Wrong parent")
+	}
+	pci := sda.Parent()
+	if pci == nil || pci.Sysname() != "0000:00:1f.2" || pci.Driver() != "ahci" || pci.Parent() != nil {
+		t.Fatal("Wrong grand parent")
+	}
+	if p := sda1.ParentWithSubsystemDevtype("pci", ""); p == nil || p.Syspath() != pci.Syspath() {
+		t.Error("Parent with subsystem not found")
+	}
+	if d, err := u.DeviceFromSyspath(f.path("sys/bus/pci/devices/0000:00:1f.2")); err != nil || d.Syspath() != pci.Syspath() {
+		t.Error("Device link not resolved", err)
+	}
+	if d, err := u.DeviceFromSubsystemSysname("net", "eth0"); err != nil || d.PropertyValue("ID_NET_NAME_PATH") != "enp0s31f2" {
+		t.Error("Network interface not found", err)
+	}
+	if d, err := u.DeviceFromDeviceID("n2"); err != nil || d.Sysname() != "eth0" {
+		t.Error("Network interface not found by ifindex", err)
+	}
+	if d, err := u.DeviceFromSubsystemSysname("mem", "null"); err != nil || d.IsInitialized() || d.Devnum() != MkDev(1, 3) {
+		t.Error("Device without database entry not read", err)
+	}
+	if _, err := u.DeviceFromSyspath("/sys/devices/virtual/mem/null"); err == nil {
+		t.Error("Device outside the tree found")
+	}
+	if _, err := u.DeviceFromSubsystemSysname("mem", "zero"); err == nil {
+		t.Error("Missing device found")
+	}
+}
+
+func TestFakeSysfsEnumerate(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	pci, err := u.DeviceFromSubsystemSysname("pci", "0000:00:1f.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		setup    func(e *Enumerate)
+		sysnames string
+	}{
+		{func(e *Enumerate) {}, "0000:00:1f.2 sda sda1 eth0 null"},
+		{func(e *Enumerate) { e.AddMatchSubsystem("block") }, "sda sda1"},
+		{func(e *Enumerate) { e.AddNomatchSubsystem("block") }, "0000:00:1f.2 eth0 null"},
+		{func(e *Enumerate) { e.AddMatchProperty("DEVTYPE", "partition") }, "sda1"},
+		{func(e *Enumerate) { e.AddMatchSysattr("size", "2*") }, "sda"},
+		{func(e *Enumerate) { e.AddMatchTag("systemd") }, "sda sda1"},
+		{func(e *Enumerate) { e.AddMatchParent(pci) }, "0000:00:1f.2 sda sda1 eth0"},
+		// Devices without a device node or network interface don't need to be initialized
+		{func(e *Enumerate) { e.AddMatchIsInitialized() }, "0000:00:1f.2 sda sda1 eth0"},
+		{func(e *Enumerate) { e.AddMatchSysname("sd*"); e.AddMatchProperty("ID_FS_TYPE", "ext4") }, "sda1"},
+	}
+	for i, test := range tests {
+		e := u.NewEnumerate()
+		test.setup(e)
+		devices, err := e.Devices()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sysnames []string
+		for _, d := range devices {
+			sysnames = append(sysnames, d.Sysname())
+		}
+		if strings.Join(sysnames, " ") != test.sysnames {
+			t.Errorf("Enumerate %d found %v", i, sysnames)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// +build linux,cgo,!purego
+
+package udev
+
+import "testing"
+
+// udev skips the test, as libudev reads the sysfs tree and udev database of the system.
+// The tests using the fake tree run with go test -tags purego.
+func (f *fakeSysfs) udev() *Udev {
+	f.t.Helper()
+	f.t.Skip("the fake sysfs tree is only read by the pure Go backend, run go test -tags purego")
+	return nil
+}
+
+// withoutCurrentTags makes devices read their current tags like with libudev before 247, until the test ends
+func withoutCurrentTags(t *testing.T) {
+	have := haveCurrentTags
+	haveCurrentTags = func() bool { return false }
+	t.Cleanup(func() { haveCurrentTags = have })
+}
@@ -0,0 +1,150 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// triggerActions are the actions the kernel accepts in a uevent file
+var triggerActions = []string{"add", "remove", "change", "move", "online", "offline", "bind", "unbind"}
+
+// TriggerOptions holds the optional arguments of a synthetic uevent
+type TriggerOptions struct {
+	// UUID is passed to the kernel if not empty, which returns it in the SYNTH_UUID property of the uevent.
+	// Kernels before 4.13 don't accept a UUID, see NewUUID.
+	UUID string
+	// Env holds extra properties passed to the kernel, which returns them as SYNTH_ARG_<key> properties of the uevent.
+	// Env is ignored unless a UUID is given.
+	Env map[string]string
+	// Monitor is used by Enumerate.Trigger, if not nil, to wait for the uevents triggered.
+	// The monitor must not be listening yet, nor filter out the devices triggered.
+	Monitor *Monitor
+}
+
+// Trigger makes the kernel send a synthetic uevent with the action given for the device,
+// like udevadm trigger, by writing the action to the uevent sys attribute.
+// The action is add, remove, change, move, online, offline, bind or unbind.
+func (d *Device) Trigger(action string) error {
+	return d.TriggerWithOptions(action, TriggerOptions{})
+}
+
+// TriggerWithOptions is like Trigger, passing the UUID and extra properties of the options to the kernel
+func (d *Device) TriggerWithOptions(action string, opts TriggerOptions) error {
+	s, err := triggerString(action, opts)
+	if err != nil {
+		return err
+	}
+	return d.SetSysattrValue("uevent", s)
+}
+
+// triggerString returns the string written to a uevent file for the action and options given
+func triggerString(action string, opts TriggerOptions) (string, error) {
+	if !slices.Contains(triggerActions, action) {
+		return "", newError("Device.Trigger", action, syscall.EINVAL)
+	}
+	if opts.UUID == "" {
+		return action, nil
+	}
+	if strings.ContainsAny(opts.UUID, " \t\n") {
+		return "", newError("Device.Trigger", opts.UUID, syscall.EINVAL)
+	}
+	var b strings.Builder
+	b.WriteString(action + " " + opts.UUID)
+	for _, k := range sortedMapKeys(opts.Env) {
+		v := opts.Env[k]
+		// The kernel splits the arguments at white space, and the keys at '='
+		if k == "" || strings.ContainsAny(k, " \t\n=") || strings.ContainsAny(v, " \t\n") {
+			return "", newError("Device.Trigger", k+"="+v, syscall.EINVAL)
+		}
+		b.WriteString(" " + k + "=" + v)
+	}
+	return b.String(), nil
+}
+
+// NewUUID returns a new random UUID, to tell the uevents triggered apart from other uevents
+func NewUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	// Version 4, variant 1
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Trigger makes the kernel send a synthetic uevent with the action given for every device enumerated,
+// and returns the devices triggered. Devices removed in the meantime are skipped, and failing to trigger
+// a device doesn't stop the others from being triggered.
+// If the options have a monitor, Trigger waits until the monitor received the uevents triggered,
+// with the UUID of the options if not empty. For a "udev" monitor, this is when udev processed them.
+// The context stops the waiting, and the monitor stops listening when Trigger returns.
+func (e *Enumerate) Trigger(ctx context.Context, action string, opts TriggerOptions) ([]*Device, error) {
+	if _, err := triggerString(action, opts); err != nil {
+		return nil, err
+	}
+	devices, err := e.Devices()
+	if err != nil {
+		return nil, err
+	}
+	var ch <-chan *Device
+	var errCh <-chan error
+	if opts.Monitor != nil {
+		// Start listening before triggering, so that no uevents are missed
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		if ch, errCh, err = opts.Monitor.DeviceErrChan(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var triggered []*Device
+	var errs []error
+	pending := make(map[string]struct{})
+	for _, d := range devices {
+		if err := d.TriggerWithOptions(action, opts); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		triggered = append(triggered, d)
+		pending[d.Syspath()] = struct{}{}
+	}
+	for ch != nil && len(pending) > 0 {
+		select {
+		case d, ok := <-ch:
+			if !ok {
+				// The monitor stopped, after sending the error which stopped it if any
+				ch = nil
+				continue
+			}
+			if d.Action() == action && (opts.UUID == "" || d.PropertyValue("SYNTH_UUID") == opts.UUID) {
+				delete(pending, d.Syspath())
+			}
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			// Uevents lost may have been uevents triggered
+			return triggered, errors.Join(append(errs, err)...)
+		case <-ctx.Done():
+			ch = nil
+		}
+	}
+	if opts.Monitor != nil && len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, newError("Enumerate.Trigger", action, syscall.EIO))
+		}
+	}
+	return triggered, errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func ExampleEnumerate_Trigger() {
+
+	// Create Udev, Enumerate and a Monitor to wait for udev to process the uevents
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("block")
+	m := u.NewMonitorFromNetlink("udev")
+
+	// Re-run the rules of all block devices
+	e.Trigger(context.Background(), "change", TriggerOptions{UUID: NewUUID(), Monitor: m})
+}
+
+func TestTriggerString(t *testing.T) {
+	tests := []struct {
+		action string
+		opts   TriggerOptions
+		want   string
+	}{
+		{"add", TriggerOptions{}, "add"},
+		{"change", TriggerOptions{Env: map[string]string{"A": "1"}}, "change"},
+		{"remove", TriggerOptions{UUID: "1234"}, "remove 1234"},
+		{"change", TriggerOptions{UUID: "1234", Env: map[string]string{"B": "2", "A": "1"}}, "change 1234 A=1 B=2"},
+	}
+	for _, test := range tests {
+		if s, err := triggerString(test.action, test.opts); err != nil || s != test.want {
+			t.Errorf("Wrong trigger %q: %v", s, err)
+		}
+	}
+	for _, test := range []struct {
+		action string
+		opts   TriggerOptions
+	}{
+		{"explode", TriggerOptions{}},
+		{"add", TriggerOptions{UUID: "12 34"}},
+		{"add", TriggerOptions{UUID: "1234", Env: map[string]string{"A=B": "1"}}},
+		{"add", TriggerOptions{UUID: "1234", Env: map[string]string{"A": "1 2"}}},
+	} {
+		if _, err := triggerString(test.action, test.opts); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("No error for %s %+v", test.action, test.opts)
+		}
+	}
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Trigger("explode"); !errors.Is(err, syscall.EINVAL) {
+		t.Error("Invalid action triggered", err)
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	a, b := NewUUID(), NewUUID()
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(a) || a == b {
+		t.Error("Wrong UUIDs", a, b)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("mem")
+	e.AddMatchSysname("null")
+	m, err := u.MonitorFromNetlink("kernel")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Waits for the kernel to send the uevent written to the uevent sys attribute of /dev/null
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	devices, err := e.Trigger(ctx, "change", TriggerOptions{UUID: NewUUID(), Monitor: m})
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS) {
+		t.Skip(err)
+	}
+	if err != nil || len(devices) != 1 {
+		t.Error("Device not triggered", devices, err)
+	}
+	null, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := null.SetSysattrValue("missing", "1"); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Missing sys attribute set", err)
+	}
+}
+
+func TestFakeSysfsTrigger(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("block")
+	devices, err := e.Trigger(context.Background(), "change", TriggerOptions{UUID: "1234", Env: map[string]string{"B": "2", "A": "1"}})
+	if err != nil || len(devices) != 2 {
+		t.Fatal("Devices not triggered", devices, err)
+	}
+	// Like sysfs, the uevent file is written without truncating it
+	b, err := os.ReadFile(filepath.Join(devices[1].Syspath(), "uevent"))
+	if err != nil || !strings.HasPrefix(string(b), "change 1234 A=1 B=2") {
+		t.Errorf("Wrong uevent written %q", b)
+	}
+	null, _ := u.DeviceFromSubsystemSysname("mem", "null")
+	if err := null.Trigger("add"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(null.Syspath(), "uevent")); !strings.HasPrefix(string(b), "add") {
+		t.Errorf("Wrong uevent written %q", b)
+	}
+}
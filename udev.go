@@ -1,6 +1,5 @@
-// +build linux,cgo
+// +build linux,cgo,!purego
 
-// Package udev provides a cgo wrapper around the libudev C library
 package udev
 
 /*
@@ -12,6 +11,7 @@ package udev
 */
 import "C"
 import (
+	"fmt"
 	"runtime"
 	"sync"
 )
@@ -64,23 +64,33 @@ func (u *Udev) newDevice(ptr *C.struct_udev_device) (d *Device) {
 
 // newMonitor is a private helper function and returns a pointer to a new monitor.
 // The monitor is also added t the monitors map in the udev context.
-// The agrument ptr is a pointer to the underlying C udev_monitor structure.
+// The agrument ptr is a pointer to the underlying C udev_monitor structure and name the name of the source.
 // The function returns nil if the pointer passed is NULL.
-func (u *Udev) newMonitor(ptr *C.struct_udev_monitor) (m *Monitor) {
+func (u *Udev) newMonitor(ptr *C.struct_udev_monitor, name string) (m *Monitor) {
 	// If passed a NULL pointer, return nil
 	if ptr == nil {
 		return nil
 	}
 	// Create a new device object
 	m = &Monitor{
-		ptr: ptr,
-		u:   u,
+		ptr:  ptr,
+		name: name,
+		u:    u,
 	}
 	runtime.SetFinalizer(m, monitorUnref)
 	// Return the device object
 	return
 }
 
+// deviceOrError is a private helper function and returns a pointer to a new device,
+// or an *Error with the errno set by libudev if the pointer passed is NULL.
+func (u *Udev) deviceOrError(ptr *C.struct_udev_device, errno error, op, arg string) (*Device, error) {
+	if ptr == nil {
+		return nil, newError(op, arg, errno)
+	}
+	return u.newDevice(ptr), nil
+}
+
 func (u *Udev) newEnumerate(ptr *C.struct_udev_enumerate) (e *Enumerate) {
 	// If passed a NULL pointer, return nil
 	if ptr == nil {
@@ -96,9 +106,8 @@ func (u *Udev) newEnumerate(ptr *C.struct_udev_enumerate) (e *Enumerate) {
 	return
 }
 
-// NewDeviceFromSyspath returns a pointer to a new device identified by its syspath, and nil on error
-// The device is identified by the syspath argument
-func (u *Udev) NewDeviceFromSyspath(syspath string) *Device {
+// DeviceFromSyspath returns a pointer to a new device identified by its syspath, and an *Error on failure
+func (u *Udev) DeviceFromSyspath(syspath string) (*Device, error) {
 	// Lock the udev context
 	u.lock()
 	defer u.unlock()
@@ -106,34 +115,64 @@ func (u *Udev) NewDeviceFromSyspath(syspath string) *Device {
 	s := C.CString(syspath)
 	defer freeCharPtr(s)
 	// Return a new device
-	return u.newDevice(C.udev_device_new_from_syspath(u.ptr, s))
+	ptr, errno := C.udev_device_new_from_syspath(u.ptr, s)
+	return u.deviceOrError(ptr, errno, "udev_device_new_from_syspath", syspath)
 }
 
-// NewDeviceFromDevnum returns a pointer to a new device identified by its Devnum, and nil on error
+// NewDeviceFromSyspath returns a pointer to a new device identified by its syspath, and nil on error
+// The device is identified by the syspath argument
+func (u *Udev) NewDeviceFromSyspath(syspath string) *Device {
+	d, _ := u.DeviceFromSyspath(syspath)
+	return d
+}
+
+// DeviceFromDevnum returns a pointer to a new device identified by its Devnum, and an *Error on failure
 // deviceType is 'c' for a character device and 'b' for a block device
-func (u *Udev) NewDeviceFromDevnum(deviceType uint8, n Devnum) *Device {
+func (u *Udev) DeviceFromDevnum(deviceType uint8, n Devnum) (*Device, error) {
 	u.lock()
 	defer u.unlock()
-	return u.newDevice(C.udev_device_new_from_devnum(u.ptr, C.char(deviceType), n.d))
+	ptr, errno := C.udev_device_new_from_devnum(u.ptr, C.char(deviceType), n.d)
+	return u.deviceOrError(ptr, errno, "udev_device_new_from_devnum", fmt.Sprintf("%c%d:%d", deviceType, n.Major(), n.Minor()))
 }
 
-// NewDeviceFromSubsystemSysname returns a pointer to a new device identified by its subystem and sysname, and nil on error
-func (u *Udev) NewDeviceFromSubsystemSysname(subsystem, sysname string) *Device {
+// NewDeviceFromDevnum returns a pointer to a new device identified by its Devnum, and nil on error
+// deviceType is 'c' for a character device and 'b' for a block device
+func (u *Udev) NewDeviceFromDevnum(deviceType uint8, n Devnum) *Device {
+	d, _ := u.DeviceFromDevnum(deviceType, n)
+	return d
+}
+
+// DeviceFromSubsystemSysname returns a pointer to a new device identified by its subystem and sysname, and an *Error on failure
+func (u *Udev) DeviceFromSubsystemSysname(subsystem, sysname string) (*Device, error) {
 	u.lock()
 	defer u.unlock()
 	ss, sn := C.CString(subsystem), C.CString(sysname)
 	defer freeCharPtr(ss)
 	defer freeCharPtr(sn)
-	return u.newDevice(C.udev_device_new_from_subsystem_sysname(u.ptr, ss, sn))
+	ptr, errno := C.udev_device_new_from_subsystem_sysname(u.ptr, ss, sn)
+	return u.deviceOrError(ptr, errno, "udev_device_new_from_subsystem_sysname", subsystem+":"+sysname)
 }
 
-// NewDeviceFromDeviceID returns a pointer to a new device identified by its device id, and nil on error
-func (u *Udev) NewDeviceFromDeviceID(id string) *Device {
+// NewDeviceFromSubsystemSysname returns a pointer to a new device identified by its subystem and sysname, and nil on error
+func (u *Udev) NewDeviceFromSubsystemSysname(subsystem, sysname string) *Device {
+	d, _ := u.DeviceFromSubsystemSysname(subsystem, sysname)
+	return d
+}
+
+// DeviceFromDeviceID returns a pointer to a new device identified by its device id, and an *Error on failure
+func (u *Udev) DeviceFromDeviceID(id string) (*Device, error) {
 	u.lock()
 	defer u.unlock()
 	i := C.CString(id)
 	defer freeCharPtr(i)
-	return u.newDevice(C.udev_device_new_from_device_id(u.ptr, i))
+	ptr, errno := C.udev_device_new_from_device_id(u.ptr, i)
+	return u.deviceOrError(ptr, errno, "udev_device_new_from_device_id", id)
+}
+
+// NewDeviceFromDeviceID returns a pointer to a new device identified by its device id, and nil on error
+func (u *Udev) NewDeviceFromDeviceID(id string) *Device {
+	d, _ := u.DeviceFromDeviceID(id)
+	return d
 }
 
 // NewEnumerate returns a pointer to a new enumerate, and nil on error
@@ -143,17 +182,28 @@ func (u *Udev) NewEnumerate() *Enumerate {
 	return u.newEnumerate(C.udev_enumerate_new(u.ptr))
 }
 
+// MonitorFromNetlink returns a pointer to a new monitor listening to a NetLink socket, and an *Error on failure
+// The name argument is either "kernel" or "udev", see NewMonitorFromNetlink.
+func (u *Udev) MonitorFromNetlink(name string) (*Monitor, error) {
+	u.lock()
+	defer u.unlock()
+	n := C.CString(name)
+	defer freeCharPtr(n)
+	ptr, errno := C.udev_monitor_new_from_netlink(u.ptr, n)
+	if ptr == nil {
+		return nil, newError("udev_monitor_new_from_netlink", name, errno)
+	}
+	return u.newMonitor(ptr, name), nil
+}
+
 // NewMonitorFromNetlink returns a pointer to a new monitor listening to a NetLink socket, and nil on error
 // The name argument is either "kernel" or "udev".
 // When passing "kernel" the events are received before they are processed by udev.
 // When passing "udev" the events are received after udev has processed the events and created device nodes.
 // In most cases you will want to use "udev".
 func (u *Udev) NewMonitorFromNetlink(name string) *Monitor {
-	u.lock()
-	defer u.unlock()
-	n := C.CString(name)
-	defer freeCharPtr(n)
-	return u.newMonitor(C.udev_monitor_new_from_netlink(u.ptr, n))
+	m, _ := u.MonitorFromNetlink(name)
+	return m
 }
 
 /*
@@ -163,6 +213,6 @@ func (u *Udev) NewMonitorFromSocket(socketPath string) *Monitor {
 	defer u.unlock()
 	s := C.CString(socketPath)
 	defer freeCharPtr(s)
-	return u.newMonitor(C.udev_monitor_new_from_socket(u.ptr, s))
+	return u.newMonitor(C.udev_monitor_new_from_socket(u.ptr, s), socketPath)
 }
 */
@@ -0,0 +1,321 @@
+//go:build linux && (!cgo || purego)
+// +build linux
+// +build !cgo purego
+
+package udev
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// sysfsPath is the mount point of sysfs
+	sysfsPath = "/sys"
+	// devPath is the directory holding device nodes
+	devPath = "/dev"
+	// udevRunPath is the runtime directory of udevd
+	udevRunPath = "/run/udev"
+)
+
+// Udev is an opaque struct holding a udev context.
+// The pure Go implementation reads sysfs and the udev database directly.
+type Udev struct {
+	// Mutex for thread sync, guarding the lazily loaded state of devices
+	m sync.Mutex
+	// Directory prepended to the paths of sysfs and the udev runtime directory, empty for the real ones
+	root string
+}
+
+// Lock locks a udev context
+func (u *Udev) lock() {
+	u.m.Lock()
+}
+
+// Unlock unlocks a udev context
+func (u *Udev) unlock() {
+	u.m.Unlock()
+}
+
+// sysfs returns the mount point of sysfs
+func (u *Udev) sysfs() string {
+	return u.root + sysfsPath
+}
+
+// udevRun returns the runtime directory of udevd
+func (u *Udev) udevRun() string {
+	return u.root + udevRunPath
+}
+
+// newDevice is a private helper function and returns a pointer to a new, empty device
+// with the syspath given.
+func (u *Udev) newDevice(syspath string) (d *Device) {
+	d = &Device{
+		u:          u,
+		properties: make(map[string]string),
+		devlinks:   make(map[string]struct{}),
+		tags:       make(map[string]struct{}),
+		sysattrs:   make(map[string]string),
+	}
+	d.setSyspath(syspath)
+	return
+}
+
+// newMonitor is a private helper function and returns a pointer to a new monitor.
+// The argument fd is the netlink socket, group the multicast group to bind to and name the name of the source.
+func (u *Udev) newMonitor(fd int, group uint32, name string) (m *Monitor) {
+	m = &Monitor{
+		fd:    fd,
+		group: group,
+		name:  name,
+		u:     u,
+	}
+	runtime.SetFinalizer(m, monitorUnref)
+	return
+}
+
+func (u *Udev) newEnumerate() (e *Enumerate) {
+	return &Enumerate{
+		u:     u,
+		added: make(map[string]struct{}),
+	}
+}
+
+// newDeviceFromSyspath creates a device from its syspath, reading sysfs and the udev database.
+func (u *Udev) newDeviceFromSyspath(syspath string) (*Device, error) {
+	if !strings.HasPrefix(syspath, u.sysfs()+"/") {
+		return nil, syscall.EINVAL
+	}
+	// Resolve symlinks, e.g. in /sys/class and /sys/bus
+	real, err := filepath.EvalSymlinks(syspath)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(real, u.sysfs()+"/") {
+		return nil, syscall.EINVAL
+	}
+	if strings.HasPrefix(real, u.sysfs()+"/devices/") {
+		// Only directories with an uevent file are devices
+		if _, err := os.Stat(filepath.Join(real, "uevent")); err != nil {
+			return nil, syscall.ENODEV
+		}
+	} else if fi, err := os.Stat(real); err != nil || !fi.IsDir() {
+		// Everything else just needs to be a directory
+		return nil, syscall.ENODEV
+	}
+	d := u.newDevice(real)
+	if err := d.readSysfs(); err != nil {
+		return nil, err
+	}
+	if err := d.readDB(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// newDeviceFromDevnum creates a device from its type ('b' or 'c') and device number.
+func (u *Udev) newDeviceFromDevnum(deviceType uint8, n Devnum) (*Device, error) {
+	var dir string
+	switch deviceType {
+	case 'b':
+		dir = "block"
+	case 'c':
+		dir = "char"
+	default:
+		return nil, syscall.EINVAL
+	}
+	return u.newDeviceFromSyspath(fmt.Sprintf("%s/dev/%s/%d:%d", u.sysfs(), dir, n.Major(), n.Minor()))
+}
+
+// newDeviceFromSubsystemSysname creates a device from its subsystem and sysname.
+func (u *Udev) newDeviceFromSubsystemSysname(subsystem, sysname string) (*Device, error) {
+	var candidates []string
+	switch subsystem {
+	case "subsystem":
+		candidates = []string{"subsystem/" + sysname, "bus/" + sysname, "class/" + sysname}
+	case "module":
+		candidates = []string{"module/" + sysname}
+	case "drivers":
+		// The sysname of a driver is "<subsystem>:<driver>"
+		i := strings.IndexByte(sysname, ':')
+		if i < 0 {
+			return nil, syscall.EINVAL
+		}
+		ss, driver := sysname[:i], sysname[i+1:]
+		candidates = []string{"subsystem/" + ss + "/drivers/" + driver, "bus/" + ss + "/drivers/" + driver}
+	default:
+		// A '/' in a sysname is represented by a '!' in sysfs
+		name := strings.Replace(sysname, "/", "!", -1)
+		candidates = []string{
+			"subsystem/" + subsystem + "/devices/" + name,
+			"bus/" + subsystem + "/devices/" + name,
+			"class/" + subsystem + "/" + name,
+			"firmware/" + subsystem + "/" + name,
+		}
+	}
+	for _, c := range candidates {
+		syspath := u.sysfs() + "/" + c
+		if _, err := os.Stat(syspath); err == nil {
+			return u.newDeviceFromSyspath(syspath)
+		}
+	}
+	return nil, syscall.ENODEV
+}
+
+// newDeviceFromIfindex creates a network device from its interface index.
+func (u *Udev) newDeviceFromIfindex(ifindex int) (*Device, error) {
+	dir := u.sysfs() + "/class/net"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name(), "ifindex"))
+		if err != nil {
+			continue
+		}
+		if i, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil && i == ifindex {
+			return u.newDeviceFromSyspath(filepath.Join(dir, e.Name()))
+		}
+	}
+	return nil, syscall.ENODEV
+}
+
+// newDeviceFromDeviceID creates a device from a device id as used by the udev database:
+// b8:0 (block device), c1:3 (character device), n3 (network interface) or +pci:0000:00:1f.2 (subsystem and sysname).
+func (u *Udev) newDeviceFromDeviceID(id string) (*Device, error) {
+	if len(id) < 2 {
+		return nil, syscall.EINVAL
+	}
+	switch id[0] {
+	case 'b', 'c':
+		var major, minor int
+		if _, err := fmt.Sscanf(id[1:], "%d:%d", &major, &minor); err != nil {
+			return nil, syscall.EINVAL
+		}
+		return u.newDeviceFromDevnum(id[0], MkDev(major, minor))
+	case 'n':
+		ifindex, err := strconv.Atoi(id[1:])
+		if err != nil || ifindex <= 0 {
+			return nil, syscall.EINVAL
+		}
+		return u.newDeviceFromIfindex(ifindex)
+	case '+':
+		i := strings.IndexByte(id, ':')
+		if i < 0 {
+			return nil, syscall.EINVAL
+		}
+		return u.newDeviceFromSubsystemSysname(id[1:i], id[i+1:])
+	}
+	return nil, syscall.EINVAL
+}
+
+// DeviceFromSyspath returns a pointer to a new device identified by its syspath, and an *Error on failure
+func (u *Udev) DeviceFromSyspath(syspath string) (*Device, error) {
+	d, err := u.newDeviceFromSyspath(syspath)
+	if err != nil {
+		return nil, newError("udev_device_new_from_syspath", syspath, err)
+	}
+	return d, nil
+}
+
+// NewDeviceFromSyspath returns a pointer to a new device identified by its syspath, and nil on error
+// The device is identified by the syspath argument
+func (u *Udev) NewDeviceFromSyspath(syspath string) *Device {
+	d, _ := u.DeviceFromSyspath(syspath)
+	return d
+}
+
+// DeviceFromDevnum returns a pointer to a new device identified by its Devnum, and an *Error on failure
+// deviceType is 'c' for a character device and 'b' for a block device
+func (u *Udev) DeviceFromDevnum(deviceType uint8, n Devnum) (*Device, error) {
+	d, err := u.newDeviceFromDevnum(deviceType, n)
+	if err != nil {
+		return nil, newError("udev_device_new_from_devnum", fmt.Sprintf("%c%d:%d", deviceType, n.Major(), n.Minor()), err)
+	}
+	return d, nil
+}
+
+// NewDeviceFromDevnum returns a pointer to a new device identified by its Devnum, and nil on error
+// deviceType is 'c' for a character device and 'b' for a block device
+func (u *Udev) NewDeviceFromDevnum(deviceType uint8, n Devnum) *Device {
+	d, _ := u.DeviceFromDevnum(deviceType, n)
+	return d
+}
+
+// DeviceFromSubsystemSysname returns a pointer to a new device identified by its subystem and sysname, and an *Error on failure
+func (u *Udev) DeviceFromSubsystemSysname(subsystem, sysname string) (*Device, error) {
+	d, err := u.newDeviceFromSubsystemSysname(subsystem, sysname)
+	if err != nil {
+		return nil, newError("udev_device_new_from_subsystem_sysname", subsystem+":"+sysname, err)
+	}
+	return d, nil
+}
+
+// NewDeviceFromSubsystemSysname returns a pointer to a new device identified by its subystem and sysname, and nil on error
+func (u *Udev) NewDeviceFromSubsystemSysname(subsystem, sysname string) *Device {
+	d, _ := u.DeviceFromSubsystemSysname(subsystem, sysname)
+	return d
+}
+
+// DeviceFromDeviceID returns a pointer to a new device identified by its device id, and an *Error on failure
+func (u *Udev) DeviceFromDeviceID(id string) (*Device, error) {
+	d, err := u.newDeviceFromDeviceID(id)
+	if err != nil {
+		return nil, newError("udev_device_new_from_device_id", id, err)
+	}
+	return d, nil
+}
+
+// NewDeviceFromDeviceID returns a pointer to a new device identified by its device id, and nil on error
+func (u *Udev) NewDeviceFromDeviceID(id string) *Device {
+	d, _ := u.DeviceFromDeviceID(id)
+	return d
+}
+
+// NewEnumerate returns a pointer to a new enumerate, and nil on error
+func (u *Udev) NewEnumerate() *Enumerate {
+	return u.newEnumerate()
+}
+
+// MonitorFromNetlink returns a pointer to a new monitor listening to a NetLink socket, and an *Error on failure
+// The name argument is either "kernel" or "udev", see NewMonitorFromNetlink.
+func (u *Udev) MonitorFromNetlink(name string) (*Monitor, error) {
+	var group uint32
+	switch name {
+	case "kernel":
+		group = netlinkGroupKernel
+	case "udev":
+		group = netlinkGroupUdev
+		// Like libudev, do not subscribe to udev events if no udevd is running,
+		// as the uevents would otherwise be those broadcast by the host into a container.
+		if _, err := os.Stat(u.udevRun() + "/control"); err != nil {
+			group = netlinkGroupNone
+		}
+	default:
+		return nil, newError("udev_monitor_new_from_netlink", name, syscall.EINVAL)
+	}
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, newError("udev_monitor_new_from_netlink", name, err)
+	}
+	return u.newMonitor(fd, group, name), nil
+}
+
+// NewMonitorFromNetlink returns a pointer to a new monitor listening to a NetLink socket, and nil on error
+// The name argument is either "kernel" or "udev".
+// When passing "kernel" the events are received before they are processed by udev.
+// When passing "udev" the events are received after udev has processed the events and created device nodes.
+// In most cases you will want to use "udev".
+func (u *Udev) NewMonitorFromNetlink(name string) *Monitor {
+	m, _ := u.MonitorFromNetlink(name)
+	return m
+}
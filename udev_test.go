@@ -51,6 +51,29 @@ func TestNewDeviceFromSyspath(t *testing.T) {
 	}
 }
 
+func ExampleUdev_DeviceFromSyspath() {
+	u := Udev{}
+	d, err := u.DeviceFromSyspath("/sys/devices/virtual/mem/random")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(d.Syspath())
+	// Output:
+	// /sys/devices/virtual/mem/random
+}
+
+func TestDeviceFromSyspath(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSyspath("/sys/devices/virtual/mem/random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Devpath() != "/devices/virtual/mem/random" {
+		t.Fail()
+	}
+}
+
 func ExampleUdev_NewDeviceFromSubsystemSysname() {
 	u := Udev{}
 	d := u.NewDeviceFromSubsystemSysname("mem", "random")
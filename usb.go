@@ -0,0 +1,237 @@
+// +build linux
+
+package udev
+
+import (
+	"slices"
+	"strconv"
+	"syscall"
+)
+
+// USBClass is the class, subclass and protocol triplet of a USB device or interface
+type USBClass struct {
+	Class    uint8
+	SubClass uint8
+	Protocol uint8
+}
+
+// USBDevice is a view of a device of the usb subsystem, either a USB device or one of its interfaces,
+// with typed accessors for the sys attributes of the kernel.
+// The identity of an interface, like its vendor and product, is that of its USB device.
+type USBDevice struct {
+	*Device
+}
+
+// NewUSBDevice returns a pointer to a new USB device view of the device, and an *Error if it is no USB device or interface
+func NewUSBDevice(d *Device) (*USBDevice, error) {
+	if d.Subsystem() != "usb" || (d.Devtype() != "usb_device" && d.Devtype() != "usb_interface") {
+		return nil, newError("NewUSBDevice", d.Syspath(), syscall.EINVAL)
+	}
+	return &USBDevice{Device: d}, nil
+}
+
+// USBDeviceOf returns the USB device of a device, like a tty, block, hidraw or net device, or nil if it is not on USB.
+// The USB device of a USB device is the device itself.
+func USBDeviceOf(d *Device) *USBDevice {
+	if d.Subsystem() == "usb" && d.Devtype() == "usb_device" {
+		return &USBDevice{Device: d}
+	}
+	if p := d.ParentWithSubsystemDevtype("usb", "usb_device"); p != nil {
+		return &USBDevice{Device: p}
+	}
+	return nil
+}
+
+// USBInterfaceOf returns the USB interface of a device, like a tty, block, hidraw or net device, or nil if it is not on USB.
+// The USB interface of a USB interface is the interface itself.
+func USBInterfaceOf(d *Device) *USBDevice {
+	if d.Subsystem() == "usb" && d.Devtype() == "usb_interface" {
+		return &USBDevice{Device: d}
+	}
+	if p := d.ParentWithSubsystemDevtype("usb", "usb_interface"); p != nil {
+		return &USBDevice{Device: p}
+	}
+	return nil
+}
+
+// IsInterface reports whether the view is of a USB interface rather than a USB device
+func (ud *USBDevice) IsInterface() bool {
+	return ud.Devtype() == "usb_interface"
+}
+
+// USBDevice returns the USB device of an interface, or the USB device itself
+func (ud *USBDevice) USBDevice() *USBDevice {
+	if !ud.IsInterface() {
+		return ud
+	}
+	return USBDeviceOf(ud.Device)
+}
+
+// Interfaces returns the interfaces of the active configuration of a USB device, ordered by interface number
+func (ud *USBDevice) Interfaces() ([]*USBDevice, error) {
+	e := ud.u.NewEnumerate()
+	if err := e.AddMatchParent(ud.Device); err != nil {
+		return nil, err
+	}
+	if err := e.AddMatchSubsystem("usb"); err != nil {
+		return nil, err
+	}
+	if err := e.AddMatchProperty("DEVTYPE", "usb_interface"); err != nil {
+		return nil, err
+	}
+	devices, err := e.Devices()
+	if err != nil {
+		return nil, err
+	}
+	var r []*USBDevice
+	for _, d := range devices {
+		// The interfaces are children of the device, which leaves out those of other USB devices below a hub
+		if p := d.Parent(); p != nil && p.Syspath() == ud.Syspath() {
+			r = append(r, &USBDevice{Device: d})
+		}
+	}
+	slices.SortStableFunc(r, func(x, y *USBDevice) int {
+		n, _ := x.InterfaceNumber()
+		m, _ := y.InterfaceNumber()
+		return n - m
+	})
+	return r, nil
+}
+
+// device returns the USB device holding the sys attributes of the identity, or an *Error for the operation
+func (ud *USBDevice) device(op string) (*Device, error) {
+	d := ud.USBDevice()
+	if d == nil {
+		return nil, newError(op, ud.Syspath(), syscall.ENODEV)
+	}
+	return d.Device, nil
+}
+
+// hexAttr parses a hexadecimal sys attribute of the USB device
+func (ud *USBDevice) hexAttr(op, sysattr string) (uint16, error) {
+	d, err := ud.device(op)
+	if err != nil {
+		return 0, err
+	}
+	n, err := sysattrUint(d, op, sysattr, 16, 16)
+	return uint16(n), err
+}
+
+// stringAttr returns a sys attribute of the USB device
+func (ud *USBDevice) stringAttr(op, sysattr string) (string, error) {
+	d, err := ud.device(op)
+	if err != nil {
+		return "", err
+	}
+	return sysattrString(d, op, sysattr)
+}
+
+// VendorID returns the vendor ID of the USB device, idVendor
+func (ud *USBDevice) VendorID() (uint16, error) {
+	return ud.hexAttr("USBDevice.VendorID", "idVendor")
+}
+
+// ProductID returns the product ID of the USB device, idProduct
+func (ud *USBDevice) ProductID() (uint16, error) {
+	return ud.hexAttr("USBDevice.ProductID", "idProduct")
+}
+
+// BCDDevice returns the release number of the USB device as binary coded decimal, bcdDevice
+func (ud *USBDevice) BCDDevice() (uint16, error) {
+	return ud.hexAttr("USBDevice.BCDDevice", "bcdDevice")
+}
+
+// Serial returns the serial number string of the USB device, which many devices don't have
+func (ud *USBDevice) Serial() (string, error) {
+	return ud.stringAttr("USBDevice.Serial", "serial")
+}
+
+// Manufacturer returns the manufacturer string of the USB device
+func (ud *USBDevice) Manufacturer() (string, error) {
+	return ud.stringAttr("USBDevice.Manufacturer", "manufacturer")
+}
+
+// Product returns the product string of the USB device
+func (ud *USBDevice) Product() (string, error) {
+	return ud.stringAttr("USBDevice.Product", "product")
+}
+
+// Speed returns the speed of the USB device in Mbit/s, like 1.5, 12, 480 or 5000
+func (ud *USBDevice) Speed() (float64, error) {
+	v, err := ud.stringAttr("USBDevice.Speed", "speed")
+	if err != nil {
+		return 0, err
+	}
+	s, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, newError("USBDevice.Speed", ud.Syspath()+"/speed", err)
+	}
+	return s, nil
+}
+
+// BusNumber returns the number of the bus of the USB device, busnum
+func (ud *USBDevice) BusNumber() (int, error) {
+	d, err := ud.device("USBDevice.BusNumber")
+	if err != nil {
+		return 0, err
+	}
+	return sysattrInt(d, "USBDevice.BusNumber", "busnum")
+}
+
+// DeviceNumber returns the address of the USB device on its bus, devnum
+func (ud *USBDevice) DeviceNumber() (int, error) {
+	d, err := ud.device("USBDevice.DeviceNumber")
+	if err != nil {
+		return 0, err
+	}
+	return sysattrInt(d, "USBDevice.DeviceNumber", "devnum")
+}
+
+// PortPath returns the ports leading from the root hub to the USB device, like 1.2 for port 2 of the hub on port 1,
+// which is 0 for a root hub
+func (ud *USBDevice) PortPath() (string, error) {
+	return ud.stringAttr("USBDevice.PortPath", "devpath")
+}
+
+// Configuration returns the active configuration of the USB device, bConfigurationValue, which is 0 if it is unconfigured
+func (ud *USBDevice) Configuration() (int, error) {
+	d, err := ud.device("USBDevice.Configuration")
+	if err != nil {
+		return 0, err
+	}
+	v, err := sysattrString(d, "USBDevice.Configuration", "bConfigurationValue")
+	if err != nil || v == "" {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, newError("USBDevice.Configuration", d.Syspath()+"/bConfigurationValue", err)
+	}
+	return n, nil
+}
+
+// Class returns the class triplet of the USB device, or of the USB interface for an interface
+func (ud *USBDevice) Class() (USBClass, error) {
+	prefix := "bDevice"
+	if ud.IsInterface() {
+		prefix = "bInterface"
+	}
+	var c USBClass
+	for _, f := range []struct {
+		sysattr string
+		v       *uint8
+	}{{"Class", &c.Class}, {"SubClass", &c.SubClass}, {"Protocol", &c.Protocol}} {
+		n, err := sysattrUint(ud.Device, "USBDevice.Class", prefix+f.sysattr, 16, 8)
+		if err != nil {
+			return USBClass{}, err
+		}
+		*f.v = uint8(n)
+	}
+	return c, nil
+}
+
+// InterfaceNumber returns the number of a USB interface, bInterfaceNumber
+func (ud *USBDevice) InterfaceNumber() (int, error) {
+	n, err := sysattrUint(ud.Device, "USBDevice.InterfaceNumber", "bInterfaceNumber", 16, 8)
+	return int(n), err
+}
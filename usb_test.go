@@ -0,0 +1,196 @@
+// +build linux
+
+package udev
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func ExampleUSBDeviceOf() {
+
+	// Create Udev and Enumerate
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("tty")
+
+	// List the serial ports on USB with the identity of their USB device
+	devices, _ := e.Devices()
+	for _, d := range devices {
+		if usb := USBDeviceOf(d); usb != nil {
+			vendor, _ := usb.VendorID()
+			product, _ := usb.ProductID()
+			serial, _ := usb.Serial()
+			fmt.Printf("%s %04x:%04x %s\n", d.Devnode(), vendor, product, serial)
+		}
+	}
+}
+
+func TestUSBDevice(t *testing.T) {
+	u := Udev{}
+	e := u.NewEnumerate()
+	e.AddMatchSubsystem("usb")
+	e.AddMatchProperty("DEVTYPE", "usb_device")
+	devices, err := e.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range devices {
+		ud, err := NewUSBDevice(d)
+		if err != nil || ud.IsInterface() {
+			t.Fatal("Wrong USB device", d.Syspath(), err)
+		}
+		interfaces, err := ud.Interfaces()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range interfaces {
+			if !i.IsInterface() || i.USBDevice().Syspath() != ud.Syspath() {
+				t.Error("Wrong interface", i.Syspath())
+			}
+		}
+	}
+	null, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Skip(err)
+	}
+	if USBDeviceOf(null) != nil || USBInterfaceOf(null) != nil {
+		t.Error("USB device of virtual device found")
+	}
+}
+
+func TestFakeSysfsUSBDevice(t *testing.T) {
+	f := newFakeSysfs(t)
+	usb := "/devices/pci0000:00/0000:00:14.0/usb1/1-1"
+	f.add(fakeDevice{
+		devpath:   usb,
+		subsystem: "usb",
+		bus:       true,
+		devtype:   "usb_device",
+		driver:    "usb",
+		sysattrs: map[string]string{
+			"idVendor": "0781", "idProduct": "5567", "bcdDevice": "0126", "manufacturer": "SanDisk", "product": "Cruzer Blade",
+			"speed": "1.5", "busnum": "1", "devnum": "4", "devpath": "1", "bConfigurationValue": "1",
+			"bDeviceClass": "00", "bDeviceSubClass": "00", "bDeviceProtocol": "00",
+		},
+	})
+	f.add(fakeDevice{
+		devpath:   usb + "/1-1:1.0",
+		subsystem: "usb",
+		bus:       true,
+		devtype:   "usb_interface",
+		driver:    "usb-storage",
+		sysattrs:  map[string]string{"bInterfaceClass": "08", "bInterfaceSubClass": "06", "bInterfaceProtocol": "50", "bInterfaceNumber": "00"},
+	})
+	f.add(fakeDevice{
+		devpath:   usb + "/1-1:1.0/host0/target0:0:0/0:0:0:0/block/sdb",
+		subsystem: "block",
+		devtype:   "disk",
+		devname:   "sdb",
+		devnum:    MkDev(8, 16),
+	})
+	u := f.udev()
+	sdb, _ := u.DeviceFromSubsystemSysname("block", "sdb")
+	d := USBDeviceOf(sdb)
+	if d == nil || d.Sysname() != "1-1" || d.IsInterface() || d.USBDevice() != d {
+		t.Fatal("USB device not found")
+	}
+	if v, err := d.VendorID(); err != nil || v != 0x0781 {
+		t.Error("Wrong vendor", v, err)
+	}
+	if p, err := d.ProductID(); err != nil || p != 0x5567 {
+		t.Error("Wrong product", p, err)
+	}
+	if b, err := d.BCDDevice(); err != nil || b != 0x0126 {
+		t.Error("Wrong release", b, err)
+	}
+	if s, err := d.Speed(); err != nil || s != 1.5 {
+		t.Error("Wrong speed", s, err)
+	}
+	if p, err := d.PortPath(); err != nil || p != "1" {
+		t.Error("Wrong port path", p, err)
+	}
+	if n, err := d.DeviceNumber(); err != nil || n != 4 {
+		t.Error("Wrong device number", n, err)
+	}
+	if c, err := d.Configuration(); err != nil || c != 1 {
+		t.Error("Wrong configuration", c, err)
+	}
+	if _, err := d.Serial(); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("No error for missing serial", err)
+	}
+	intf := USBInterfaceOf(sdb)
+	if intf == nil || !intf.IsInterface() || intf.Driver() != "usb-storage" || intf.USBDevice().Syspath() != d.Syspath() {
+		t.Fatal("USB interface not found")
+	}
+	// The identity of an interface is that of its device
+	if p, err := intf.Product(); err != nil || p != "Cruzer Blade" {
+		t.Error("Wrong product", p, err)
+	}
+	if c, err := intf.Class(); err != nil || c != (USBClass{0x08, 0x06, 0x50}) {
+		t.Error("Wrong interface class", c, err)
+	}
+	if c, err := d.Class(); err != nil || c != (USBClass{}) {
+		t.Error("Wrong device class", c, err)
+	}
+	interfaces, err := d.Interfaces()
+	if err != nil || len(interfaces) != 1 || interfaces[0].Syspath() != intf.Syspath() {
+		t.Error("Wrong interfaces", interfaces, err)
+	}
+	// The interfaces of a device below a hub are not those of the hub
+	hub := "/devices/pci0000:00/0000:00:14.0/usb1/1-2"
+	for _, h := range []fakeDevice{
+		{devpath: hub, devtype: "usb_device"},
+		{devpath: hub + "/1-2:1.0", devtype: "usb_interface", sysattrs: map[string]string{"bInterfaceNumber": "00"}},
+		{devpath: hub + "/1-2:1.0/1-2.1", devtype: "usb_device"},
+		{devpath: hub + "/1-2:1.0/1-2.1/1-2.1:1.0", devtype: "usb_interface", sysattrs: map[string]string{"bInterfaceNumber": "00"}},
+		{devpath: hub + "/1-2:1.0/1-2.1/1-2.1:1.1", devtype: "usb_interface", sysattrs: map[string]string{"bInterfaceNumber": "01"}},
+	} {
+		h.subsystem, h.bus = "usb", true
+		f.add(h)
+	}
+	// The interfaces of a root hub are named after its port 0 rather than after the hub
+	root := "/devices/pci0000:00/0000:00:14.0/usb1"
+	for _, h := range []fakeDevice{
+		{devpath: root, devtype: "usb_device"},
+		{devpath: root + "/1-0:1.0", devtype: "usb_interface", sysattrs: map[string]string{"bInterfaceNumber": "00"}},
+	} {
+		h.subsystem, h.bus = "usb", true
+		f.add(h)
+	}
+	for _, test := range []struct {
+		sysname    string
+		interfaces string
+	}{
+		{"usb1", "1-0:1.0"},
+		{"1-2", "1-2:1.0"},
+		{"1-2.1", "1-2.1:1.0 1-2.1:1.1"},
+	} {
+		d, _ := u.DeviceFromSubsystemSysname("usb", test.sysname)
+		ud, err := NewUSBDevice(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		interfaces, err := ud.Interfaces()
+		var sysnames []string
+		for _, i := range interfaces {
+			sysnames = append(sysnames, i.Sysname())
+		}
+		if err != nil || strings.Join(sysnames, " ") != test.interfaces {
+			t.Error("Wrong interfaces of", test.sysname, sysnames, err)
+		}
+	}
+	if _, err := NewUSBDevice(sdb); err == nil {
+		t.Error("No error for block device")
+	}
+	if d, err := NewUSBDevice(intf.Device); err != nil || !d.IsInterface() {
+		t.Error("Interface not viewed", err)
+	}
+	null, _ := newTestSysfs(t).udev().DeviceFromSubsystemSysname("mem", "null")
+	if USBDeviceOf(null) != nil || USBInterfaceOf(null) != nil {
+		t.Error("USB device of virtual device found")
+	}
+}
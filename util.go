@@ -1,4 +1,4 @@
-// +build linux,cgo
+// +build linux,cgo,!purego
 
 package udev
 
@@ -11,8 +11,50 @@ package udev
 */
 import "C"
 
-import "unsafe"
+import (
+	"iter"
+	"syscall"
+	"unsafe"
+)
 
 func freeCharPtr(s *C.char) {
 	C.free(unsafe.Pointer(s))
 }
+
+// errorFromReturn returns an *Error for the negative errno returned by a libudev function, and nil on success
+func errorFromReturn(r C.int, op, arg string) error {
+	if r >= 0 {
+		return nil
+	}
+	return &Error{Op: op, Arg: arg, Err: syscall.Errno(-r)}
+}
+
+// listEntries returns an iter.Seq2 over the names and values of a libudev list.
+// The function first is called to retrieve the first list entry. The udev context is locked
+// while the list is read, but not while the values are yielded.
+func (u *Udev) listEntries(first func() *C.struct_udev_list_entry) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		u.m.Lock()
+		l := first()
+		u.m.Unlock()
+		for l != nil {
+			u.m.Lock()
+			name := C.GoString(C.udev_list_entry_get_name(l))
+			value := C.GoString(C.udev_list_entry_get_value(l))
+			l = C.udev_list_entry_get_next(l)
+			u.m.Unlock()
+			if !yield(name, value) {
+				return
+			}
+		}
+	}
+}
+
+// listNames returns an iter.Seq over the names of a libudev list, see listEntries.
+func (u *Udev) listNames(first func() *C.struct_udev_list_entry) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		u.listEntries(first)(func(name, _ string) bool {
+			return yield(name)
+		})
+	}
+}
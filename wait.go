@@ -0,0 +1,173 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitTimeout is the timeout in milliseconds of a single wait of waitReadable
+const waitTimeout = 100
+
+// waitReadable waits until the fd is readable or a short timeout expired, and returns an error if the context is done
+func waitReadable(ctx context.Context, fd int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	if _, err := unix.Poll(fds, waitTimeout); err != nil && err != unix.EINTR {
+		return &Error{Op: "unix.Poll", Err: err}
+	}
+	return ctx.Err()
+}
+
+// WaitForDevice returns a device matching the filter which was initialized by udev, waiting until one is added
+// if there is none yet, or until the context is done.
+func (u *Udev) WaitForDevice(ctx context.Context, f Filter) (*Device, error) {
+	f.IsInitialized = true
+	// Start monitoring before enumerating, so that no device is missed
+	m, err := u.MonitorFromNetlink("udev")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Install(m); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, errs, err := m.DeviceErrChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if d, err := u.firstDevice(&f); d != nil || err != nil {
+		return d, err
+	}
+	for {
+		select {
+		case d, ok := <-ch:
+			if !ok {
+				return nil, ctx.Err()
+			}
+			// The devices sent by udev are initialized, and the filter was evaluated by the monitor
+			if d.Action() != "remove" {
+				return d, nil
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if !errors.Is(err, ErrOverrun) {
+				return nil, err
+			}
+			// Lost events may have added a matching device
+			if d, err := u.firstDevice(&f); d != nil || err != nil {
+				return d, err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// firstDevice enumerates the devices matching the filter, and returns the first one or nil if there is none
+func (u *Udev) firstDevice(f *Filter) (*Device, error) {
+	e := u.NewEnumerate()
+	if err := f.Apply(e); err != nil {
+		return nil, err
+	}
+	devices, err := e.Devices()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range devices {
+		if f.Match(d) {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+// WaitInitialized waits until udev initialized the device, or until the context is done, and returns
+// the device read again from sysfs and the udev database. The device itself is not updated.
+// A device renamed meanwhile is followed to its new syspath.
+// An error satisfying errors.Is(err, fs.ErrNotExist) is returned if the device is removed first.
+func (d *Device) WaitInitialized(ctx context.Context) (*Device, error) {
+	// Start monitoring before reading the device again, so that its event is not missed
+	m, err := d.u.MonitorFromNetlink("udev")
+	if err != nil {
+		return nil, err
+	}
+	if s := d.Subsystem(); s != "" {
+		if err := m.FilterAddMatchSubsystem(s); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, errs, err := m.DeviceErrChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	syspath := d.Syspath()
+	refresh := func() (*Device, error) {
+		r, err := d.u.DeviceFromSyspath(syspath)
+		if err != nil || !r.IsInitialized() {
+			return nil, err
+		}
+		return r, nil
+	}
+	if r, err := refresh(); r != nil || err != nil {
+		return r, err
+	}
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return nil, ctx.Err()
+			}
+			if syspath, ok = followDevice(e, syspath); !ok {
+				continue
+			}
+			if e.Action() == "remove" {
+				return nil, newError("Device.WaitInitialized", syspath, syscall.ENODEV)
+			}
+			if r, err := refresh(); r != nil || err != nil {
+				return r, err
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if !errors.Is(err, ErrOverrun) {
+				return nil, err
+			}
+			// The event of the device may have been lost
+			if r, err := refresh(); r != nil || err != nil {
+				return r, err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// followDevice reports whether the event is about the device at the syspath given, and returns the syspath of the
+// device after the event, which differs for the move event of a device renamed from the syspath given
+func followDevice(e DeviceInfo, syspath string) (string, bool) {
+	if e.Syspath() == syspath {
+		return syspath, true
+	}
+	// DEVPATH_OLD is the devpath before the move, relative to the sysfs mount point like the devpath
+	if old := e.PropertyValue("DEVPATH_OLD"); e.Action() == "move" && old != "" &&
+		strings.TrimSuffix(e.Syspath(), e.Devpath())+old == syspath {
+		return e.Syspath(), true
+	}
+	return syspath, false
+}
@@ -0,0 +1,92 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+)
+
+func ExampleDevice_WaitInitialized() {
+
+	// Create Udev and Device
+	u := Udev{}
+	d, _ := u.DeviceFromSubsystemSysname("mem", "null")
+
+	// Wait up to 10 seconds for udev to process the device
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if !d.IsInitialized() {
+		d, _ = d.WaitInitialized(ctx)
+	}
+}
+
+func TestWaitInitialized(t *testing.T) {
+	u := Udev{}
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Without udevd, the device is never initialized
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r, err := d.WaitInitialized(ctx)
+	switch {
+	case d.IsInitialized() && (err != nil || r.Syspath() != d.Syspath() || !r.IsInitialized()):
+		t.Error("Device not refreshed", err)
+	case !d.IsInitialized() && r == nil && !errors.Is(err, context.DeadlineExceeded):
+		t.Error(err)
+	}
+}
+
+func TestFollowDevice(t *testing.T) {
+	event := func(action, devpath, old string) *MemDevice {
+		properties := map[string]string{"ACTION": action, "DEVPATH": devpath, "SUBSYSTEM": "net"}
+		if old != "" {
+			properties["DEVPATH_OLD"] = old
+		}
+		return NewMemDevice(nil, properties, nil)
+	}
+	tests := []struct {
+		event   *MemDevice
+		syspath string
+		ok      bool
+	}{
+		{event("add", "/devices/virtual/net/eth0", ""), "/sys/devices/virtual/net/eth0", true},
+		{event("add", "/devices/virtual/net/eth1", ""), "/sys/devices/virtual/net/eth0", false},
+		// The device is renamed before udev processed it
+		{event("move", "/devices/virtual/net/lan0", "/devices/virtual/net/eth0"), "/sys/devices/virtual/net/lan0", true},
+		{event("move", "/devices/virtual/net/lan1", "/devices/virtual/net/eth1"), "/sys/devices/virtual/net/eth0", false},
+		{event("change", "/devices/virtual/net/lan0", "/devices/virtual/net/eth0"), "/sys/devices/virtual/net/eth0", false},
+	}
+	for _, test := range tests {
+		if syspath, ok := followDevice(test.event, "/sys/devices/virtual/net/eth0"); syspath != test.syspath || ok != test.ok {
+			t.Errorf("%s %s followed to %s %v", test.event.Action(), test.event.Devpath(), syspath, ok)
+		}
+	}
+}
+
+func TestFakeSysfsWaitInitialized(t *testing.T) {
+	f := newTestSysfs(t)
+	u := f.udev()
+	sda1, _ := u.DeviceFromSubsystemSysname("block", "sda1")
+	d, err := sda1.WaitInitialized(context.Background())
+	if err != nil || d == sda1 || d.Syspath() != sda1.Syspath() || !d.IsInitialized() {
+		t.Error("Device not refreshed", err)
+	}
+	// Without a database entry, the device node is not set up yet
+	null, _ := u.DeviceFromSubsystemSysname("mem", "null")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if d, err := null.WaitInitialized(ctx); d != nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Device initialized", err)
+	}
+	os.RemoveAll(f.path("sys/devices/virtual/mem/null"))
+	if d, err := null.WaitInitialized(context.Background()); d != nil || !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Removed device initialized", err)
+	}
+}
@@ -0,0 +1,259 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WatchEventType is the type of a WatchEvent
+type WatchEventType int
+
+const (
+	// Added is sent for a device which was enumerated, was added or started to match the filter
+	Added WatchEventType = iota
+	// Changed is sent for a device which changed
+	Changed
+	// Removed is sent for a device which was removed or no longer matches the filter
+	Removed
+	// Synced is sent when the initial enumeration, or a re-enumeration after events were lost, is complete
+	Synced
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Changed:
+		return "changed"
+	case Removed:
+		return "removed"
+	case Synced:
+		return "synced"
+	}
+	return "unknown"
+}
+
+// WatchEvent is an event sent by a Watcher
+type WatchEvent struct {
+	Type WatchEventType
+	// Device is the device the event is about, and nil for Synced.
+	// The devices enumerated and received from the monitor are *Device values.
+	Device DeviceInfo
+}
+
+// Watcher combines the enumeration of the devices matching a filter with a udev monitor,
+// and sends a consistent stream of events for these devices.
+type Watcher struct {
+	u      *Udev
+	filter Filter
+
+	// Mutex guarding the error which stopped the watcher
+	m   sync.Mutex
+	err error
+}
+
+// NewWatcher returns a pointer to a new watcher for the devices matching the filter
+func (u *Udev) NewWatcher(f Filter) *Watcher {
+	return &Watcher{
+		u:      u,
+		filter: f,
+	}
+}
+
+// EventChan starts a udev monitor, enumerates the devices matching the filter and spawns a goroutine
+// sending events on the returned channel.
+// An Added event is sent for each device enumerated, followed by a Synced event. After that, the
+// events received by the monitor are sent, with duplicates of the enumerated devices removed.
+// If uevents were lost, the devices are enumerated again, the differences are sent as Added, Changed
+// and Removed events, followed by another Synced event.
+// The function takes a context as argument, which when done will stop the goroutine and close the
+// channel. If the goroutine stops because of an error, the channel is closed and Err returns the error.
+func (w *Watcher) EventChan(ctx context.Context) (<-chan WatchEvent, error) {
+	// Start monitoring before enumerating, so that no events are missed
+	m, err := w.u.MonitorFromNetlink("udev")
+	if err != nil {
+		return nil, err
+	}
+	// Only install the matches which can't change for a device, to receive the events of devices
+	// which no longer match the filter
+	f := Filter{Subsystems: w.filter.Subsystems, NomatchSubsystems: w.filter.NomatchSubsystems, Devtype: w.filter.Devtype}
+	if err = f.Install(m); err != nil {
+		m.close()
+		return nil, err
+	}
+	// The monitor goroutine stops, and the monitor can be released, whenever the event goroutine stops
+	ctx, cancel := context.WithCancel(ctx)
+	devices, errs, err := m.DeviceInfoChan(ctx, ChanOptions{Errors: true})
+	if err != nil {
+		cancel()
+		m.close()
+		return nil, err
+	}
+
+	w.setErr(nil)
+	ch := make(chan WatchEvent)
+	send := func(ev WatchEvent) bool {
+		select {
+		case ch <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(ch)
+		defer cancel()
+		known := make(map[string]DeviceInfo)
+		if ok, err := w.sync(known, send); !ok {
+			w.setErr(err)
+			return
+		}
+		for {
+			select {
+			case d, ok := <-devices:
+				if !ok {
+					return
+				}
+				if !w.handle(known, d, send) {
+					return
+				}
+			case err, ok := <-errs:
+				if !ok {
+					return
+				}
+				if !errors.Is(err, ErrOverrun) {
+					w.setErr(err)
+					return
+				}
+				if ok, err := w.sync(known, send); !ok {
+					w.setErr(err)
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Err returns the error which stopped the goroutine started by EventChan, or nil if the context is done
+func (w *Watcher) Err() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.err
+}
+
+func (w *Watcher) setErr(err error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	w.err = err
+}
+
+// sync enumerates the devices matching the filter, sends the differences to the known devices and a Synced event.
+// It returns false if the goroutine has to stop, along with the error if there was one.
+func (w *Watcher) sync(known map[string]DeviceInfo, send func(WatchEvent) bool) (bool, error) {
+	e := w.u.NewEnumerate()
+	if err := w.filter.Apply(e); err != nil {
+		return false, err
+	}
+	if err := e.AddMatchIsInitialized(); err != nil {
+		return false, err
+	}
+	syspaths, err := e.DeviceSyspaths()
+	if err != nil {
+		return false, err
+	}
+	current := make(map[string]struct{}, len(syspaths))
+	for _, syspath := range syspaths {
+		// Devices may have been removed since scanning
+		d, err := w.u.DeviceFromSyspath(syspath)
+		if err != nil || !w.filter.Match(d) {
+			continue
+		}
+		current[syspath] = struct{}{}
+		old, ok := known[syspath]
+		known[syspath] = d
+		if !ok {
+			if !send(WatchEvent{Type: Added, Device: d}) {
+				return false, nil
+			}
+		} else if !sameProperties(old, d) {
+			if !send(WatchEvent{Type: Changed, Device: d}) {
+				return false, nil
+			}
+		}
+	}
+	removed := make([]string, 0)
+	for syspath := range known {
+		if _, ok := current[syspath]; !ok {
+			removed = append(removed, syspath)
+		}
+	}
+	sort.Strings(removed)
+	for _, syspath := range removed {
+		d := known[syspath]
+		delete(known, syspath)
+		if !send(WatchEvent{Type: Removed, Device: d}) {
+			return false, nil
+		}
+	}
+	return send(WatchEvent{Type: Synced}), nil
+}
+
+// handle updates the known devices with a device received by the monitor and sends the resulting event.
+// It returns false if the context is done.
+func (w *Watcher) handle(known map[string]DeviceInfo, d DeviceInfo, send func(WatchEvent) bool) bool {
+	syspath := d.Syspath()
+	// A moved device is removed from its old syspath
+	if devpath := d.PropertyValue("DEVPATH_OLD"); devpath != "" {
+		old := strings.TrimSuffix(syspath, d.Devpath()) + devpath
+		if od, ok := known[old]; ok {
+			delete(known, old)
+			if !send(WatchEvent{Type: Removed, Device: od}) {
+				return false
+			}
+		}
+	}
+	old, ok := known[syspath]
+	switch {
+	case d.Action() == "remove" || !w.filter.Match(d):
+		if !ok {
+			return true
+		}
+		delete(known, syspath)
+		return send(WatchEvent{Type: Removed, Device: d})
+	case !ok:
+		known[syspath] = d
+		return send(WatchEvent{Type: Added, Device: d})
+	}
+	known[syspath] = d
+	// An add event for an enumerated device is a duplicate, unless the device changed in between
+	if d.Action() == "add" && sameProperties(old, d) {
+		return true
+	}
+	return send(WatchEvent{Type: Changed, Device: d})
+}
+
+// sameProperties reports whether two devices have the same properties, ignoring those of the event
+func sameProperties(a, b DeviceInfo) bool {
+	pa, pb := a.Properties(), b.Properties()
+	for _, k := range []string{"ACTION", "SEQNUM", "DEVPATH_OLD"} {
+		delete(pa, k)
+		delete(pb, k)
+	}
+	if len(pa) != len(pb) {
+		return false
+	}
+	for k, v := range pa {
+		if pb[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+// +build linux
+
+package udev
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func ExampleWatcher() {
+
+	// Create Udev and Watcher for block disks
+	u := Udev{}
+	w := u.NewWatcher(Filter{Subsystems: []string{"block"}, Devtype: "disk"})
+
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Start the watcher and receive events until the context is done
+	ch, _ := w.EventChan(ctx)
+	for ev := range ch {
+		if ev.Type == Synced {
+			fmt.Println("Initial enumeration complete")
+			continue
+		}
+		fmt.Println(ev.Type, ev.Device.Syspath())
+	}
+	if err := w.Err(); err != nil {
+		fmt.Println("Watcher stopped:", err)
+	}
+}
+
+func TestWatcherEventChan(t *testing.T) {
+	u := Udev{}
+	f := Filter{Subsystems: []string{"mem"}}
+	w := u.NewWatcher(f)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ch, err := w.EventChan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	synced := false
+	for ev := range ch {
+		switch {
+		case ev.Type == Synced:
+			synced = true
+		case ev.Device == nil:
+			t.Error("Event without device")
+		case !f.Match(ev.Device):
+			t.Error("Device not matching", ev.Device.Syspath())
+		case !synced && ev.Type != Added:
+			t.Error("Enumeration sent", ev.Type)
+		}
+	}
+	if !synced {
+		t.Error("Not synced")
+	}
+	if w.Err() != nil {
+		t.Error(w.Err())
+	}
+}
+
+func TestWatcherHandle(t *testing.T) {
+	u := Udev{}
+	w := u.NewWatcher(Filter{Subsystems: []string{"mem"}})
+	d, err := u.DeviceFromSubsystemSysname("mem", "null")
+	if err != nil {
+		t.Skip(err)
+	}
+	var events []WatchEvent
+	send := func(ev WatchEvent) bool {
+		events = append(events, ev)
+		return true
+	}
+	known := make(map[string]DeviceInfo)
+	if ok, err := w.sync(known, send); !ok || err != nil {
+		t.Fatal(err)
+	}
+	events = nil
+	// Devices which are known or do not match are not sent
+	if _, ok := known[d.Syspath()]; ok {
+		w.handle(known, d, send)
+		if len(events) != 0 {
+			t.Error("Duplicate sent")
+		}
+	}
+	if o, err := u.DeviceFromSubsystemSysname("net", "lo"); err == nil {
+		w.handle(known, o, send)
+		if len(events) != 0 {
+			t.Error("Device not matching sent")
+		}
+	}
+	delete(known, d.Syspath())
+	w.handle(known, d, send)
+	if len(events) != 1 || events[0].Type != Added || events[0].Device != d {
+		t.Error("Added not sent")
+	}
+}